@@ -0,0 +1,113 @@
+package mongo
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const requestIDHeader = "X-Request-ID"
+
+// withRequestContext stores the request ID, the principal and IP of the caller and the
+// response metadata in the request context. The request ID is taken from the X-Request-ID
+// header or generated, the IP from X-Forwarded-For of trusted proxies. Errors of the handlers
+// are logged with logger unless the access log records them.
+func withRequestContext(principal PrincipalFunc, envelope string, logger Logger, trustedProxies []*net.IPNet, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestID := r.Header.Get(requestIDHeader)
+		if requestID == "" {
+			requestID = uuid.New().String()
+		}
+		w.Header().Set(requestIDHeader, requestID)
+		ctx := WithRequestID(r.Context(), requestID)
+		ctx = WithPrincipal(ctx, principal(r))
+		ctx = WithClientIP(ctx, clientIP(r, trustedProxies))
+		ctx = withResponseMeta(ctx, envelope)
+		ctx = context.WithValue(ctx, requestLogKey, &requestLog{logger: logger, requestID: requestID})
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// requestLog holds the error of a request until the access log writes it, or logs it right
+// away if there is no access log. It is kept in the request context, so wrapped response
+// writers of middleware do not hide it.
+type requestLog struct {
+	mu        sync.Mutex
+	logger    Logger
+	requestID string
+	accessLog bool
+	err       error
+}
+
+func requestLogFromContext(ctx context.Context) *requestLog {
+	log, _ := ctx.Value(requestLogKey).(*requestLog)
+	return log
+}
+
+// record keeps err for the access log or logs it.
+func (l *requestLog) record(err error, fields ...interface{}) {
+	l.mu.Lock()
+	l.err = err
+	logged := l.accessLog
+	l.mu.Unlock()
+	if !logged {
+		l.logger.Error(err.Error(), append([]interface{}{"requestId", l.requestID}, fields...)...)
+	}
+}
+
+func (l *requestLog) error() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.err
+}
+
+func accessLog(logger Logger, route string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		log := requestLogFromContext(r.Context())
+		if log == nil {
+			log = &requestLog{logger: logger, requestID: RequestIDFromContext(r.Context())}
+			r = r.WithContext(context.WithValue(r.Context(), requestLogKey, log))
+		}
+		log.mu.Lock()
+		log.accessLog = true
+		log.mu.Unlock()
+		recorder := &statusRecorder{ResponseWriter: w}
+		next.ServeHTTP(recorder, r)
+		err := log.error()
+		fields := []interface{}{
+			"requestId", RequestIDFromContext(r.Context()),
+			"principal", PrincipalFromContext(r.Context()),
+			"method", r.Method,
+			"route", route,
+			"path", r.URL.Path,
+			"status", recorder.Status(),
+			"durationMs", float64(time.Since(start).Microseconds()) / 1000,
+		}
+		if err != nil {
+			fields = append(fields, "error", err.Error())
+		}
+		switch {
+		case recorder.Status() >= http.StatusInternalServerError:
+			logger.Error("request", fields...)
+		case err != nil:
+			logger.Warn("request", fields...)
+		default:
+			logger.Info("request", fields...)
+		}
+	})
+}
+
+// logError attaches err to the access log entry of the request, if there is one, or logs it
+// with the logger of the server.
+func logError(r *http.Request, err error) {
+	log := requestLogFromContext(r.Context())
+	if log == nil {
+		defaultLogger().Error(err.Error())
+		return
+	}
+	log.record(err)
+}
@@ -0,0 +1,18 @@
+package mongo
+
+const adminPath = "/_admin"
+
+// AdminRoutes returns the routes of all enabled admin features, they are part of GetRoutes.
+func AdminRoutes(mongoClient *MongoClient) []Route {
+	routes := make([]Route, 0)
+	if mongoClient.slowQueries != nil {
+		routes = append(routes, Route{Path: adminPath + "/slowqueries", HandlerFc: GetSlowQueries(mongoClient), Methods: "GET", Group: RouteGroupAdmin})
+	}
+	if mongoClient.audit != nil && mongoClient.audit.collection != nil {
+		routes = append(routes, Route{Path: adminPath + "/audit", HandlerFc: GetAuditRecords(mongoClient), Methods: "GET", Group: RouteGroupAdmin})
+	}
+	if mongoClient.webhooks != nil {
+		routes = append(routes, Route{Path: adminPath + "/webhooks/deliveries", HandlerFc: GetWebhookDeliveries(mongoClient), Methods: "GET", Group: RouteGroupAdmin})
+	}
+	return routes
+}
@@ -0,0 +1,274 @@
+package mongo
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"io"
+	"net/http"
+	"os"
+	"reflect"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	AuditInsert         = "insert"
+	AuditReplace        = "replace"
+	AuditUpdate         = "update"
+	AuditDelete         = "delete"
+	AuditDropCollection = "dropCollection"
+	AuditDropDatabase   = "dropDatabase"
+
+	defaultAuditDatabase   = "admin"
+	defaultAuditCollection = "audit"
+	defaultAuditLimit      = 100
+
+	defaultAuditWebhookTimeout = 10 * time.Second
+)
+
+var errAuditNotQueryable = errors.New("audit log is not enabled or not stored in a collection")
+
+// AuditConfig enables the audit log of all mutations done through the MongoClient. Records
+// are stored in Database.Collection unless a Sink is set. Images adds the complete documents
+// before and after the mutation, the diff of the top level fields is always recorded.
+type AuditConfig struct {
+	Sink       AuditSink
+	Database   string
+	Collection string
+	Images     bool
+}
+
+type AuditRecord struct {
+	Timestamp  time.Time   `bson:"ts"`
+	Principal  string      `bson:"principal,omitempty"`
+	RequestID  string      `bson:"requestId,omitempty"`
+	Operation  string      `bson:"op"`
+	Database   string      `bson:"database"`
+	Collection string      `bson:"collection,omitempty"`
+	DocumentID interface{} `bson:"documentId,omitempty"`
+	Diff       bson.M      `bson:"diff,omitempty"`
+	Before     bson.M      `bson:"before,omitempty"`
+	After      bson.M      `bson:"after,omitempty"`
+}
+
+// AuditSink receives the audit records, e.g. to write them to a file or another service.
+type AuditSink interface {
+	Write(ctx context.Context, record AuditRecord) error
+}
+
+type collectionAuditSink struct {
+	collection *mongo.Collection
+}
+
+func (s collectionAuditSink) Write(ctx context.Context, record AuditRecord) error {
+	_, err := s.collection.InsertOne(ctx, record)
+	return err
+}
+
+type writerAuditSink struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// NewWriterAuditSink writes the records as extended JSON lines, e.g. to os.Stdout.
+func NewWriterAuditSink(w io.Writer) AuditSink {
+	return &writerAuditSink{w: w}
+}
+
+// NewFileAuditSink appends the records as extended JSON lines to the file at path.
+func NewFileAuditSink(path string) (AuditSink, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, err
+	}
+	return NewWriterAuditSink(f), nil
+}
+
+func (s *writerAuditSink) Write(ctx context.Context, record AuditRecord) error {
+	data, err := bson.MarshalExtJSON(record, false, false)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, err = s.w.Write(append(data, '\n'))
+	return err
+}
+
+type webhookAuditSink struct {
+	url    string
+	client *http.Client
+}
+
+// NewWebhookAuditSink posts every record as extended JSON to url, a nil client uses a client
+// with a timeout of 10 seconds.
+func NewWebhookAuditSink(url string, client *http.Client) AuditSink {
+	if client == nil {
+		client = &http.Client{Timeout: defaultAuditWebhookTimeout}
+	}
+	return webhookAuditSink{url: url, client: client}
+}
+
+func (s webhookAuditSink) Write(ctx context.Context, record AuditRecord) error {
+	data, err := bson.MarshalExtJSON(record, false, false)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", jsonContentType)
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= http.StatusMultipleChoices {
+		return fmt.Errorf("audit webhook answered %s", resp.Status)
+	}
+	return nil
+}
+
+type auditLog struct {
+	config     AuditConfig
+	collection *mongo.Collection
+	sink       AuditSink
+}
+
+func newAuditLog(config AuditConfig, client *mongo.Client) *auditLog {
+	if config.Database == "" {
+		config.Database = defaultAuditDatabase
+	}
+	if config.Collection == "" {
+		config.Collection = defaultAuditCollection
+	}
+	l := &auditLog{config: config, sink: config.Sink}
+	if l.sink == nil {
+		l.collection = client.Database(config.Database).Collection(config.Collection)
+		l.sink = collectionAuditSink{collection: l.collection}
+	}
+	return l
+}
+
+// auditAfter returns the document with the _id of doc if the audit log or webhooks are
+// enabled.
+func (b MongoClient) auditAfter(col *mongo.Collection, doc bson.M) bson.M {
+	if b.audit == nil && !b.publishesChanges() || doc == nil {
+		return nil
+	}
+	after, _ := findOne(b.Context(), col, bson.M{documentIDField: doc[documentIDField]})
+	return after
+}
+
+// auditRecord writes an audit record of a mutation and publishes it to the webhooks. Failures
+// are logged only, the mutation has already happened.
+func (b MongoClient) auditRecord(op, database, collection string, id interface{}, before, after bson.M) {
+	b.publishChange(op, database, collection, id, before, after)
+	if b.audit == nil {
+		return
+	}
+	ctx := b.Context()
+	record := AuditRecord{
+		Timestamp:  time.Now(),
+		Principal:  PrincipalFromContext(ctx),
+		RequestID:  RequestIDFromContext(ctx),
+		Operation:  op,
+		Database:   database,
+		Collection: collection,
+		DocumentID: id,
+	}
+	if before != nil && after != nil {
+		record.Diff = auditDiff(before, after)
+	}
+	if b.audit.config.Images {
+		record.Before = before
+		record.After = after
+	}
+	// the request may be cancelled right after the mutation, the record must still be written,
+	// but a hanging sink must not block the request for good
+	writeCtx, cancel := context.WithTimeout(Ctx(), b.timeout())
+	defer cancel()
+	if err := b.audit.sink.Write(writeCtx, record); err != nil {
+		b.logger().Error("writing audit record failed", "error", err, "op", op, "database", database, "collection", collection)
+	}
+}
+
+// auditDiff returns the top level fields set or changed by the mutation and the removed ones.
+func auditDiff(before, after bson.M) bson.M {
+	set := bson.M{}
+	for k, v := range after {
+		if old, ok := before[k]; !ok || !reflect.DeepEqual(old, v) {
+			set[k] = v
+		}
+	}
+	unset := bson.A{}
+	for k := range before {
+		if _, ok := after[k]; !ok {
+			unset = append(unset, k)
+		}
+	}
+	diff := bson.M{}
+	if len(set) > 0 {
+		diff["set"] = set
+	}
+	if len(unset) > 0 {
+		diff["unset"] = unset
+	}
+	return diff
+}
+
+func (b MongoClient) AuditRecords(filter bson.M, limit int64) ([]AuditRecord, error) {
+	if b.audit == nil || b.audit.collection == nil {
+		return nil, errAuditNotQueryable
+	}
+	opts := options.Find().SetSort(bson.M{"ts": -1}).SetLimit(limit)
+	cursor, err := b.audit.collection.Find(b.Context(), filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]AuditRecord, 0)
+	err = cursor.All(b.Context(), &result)
+	return result, err
+}
+
+// GetAuditRecords answers with the latest audit records, filtered by the database,
+// collection, op, principal and documentId query parameters.
+func GetAuditRecords(mongoClient *MongoClient) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		client := mongoClient.WithContext(r.Context())
+		query := r.URL.Query()
+		limit := int64(defaultAuditLimit)
+		if v := query.Get(limitParam); v != "" {
+			parsed, err := strconv.ParseInt(v, 10, 64)
+			if checkError(err, w, r) {
+				return
+			}
+			limit = parsed
+		}
+		filter := bson.M{}
+		for _, field := range []string{"database", "collection", "op", "principal"} {
+			if v := query.Get(field); v != "" {
+				filter[field] = v
+			}
+		}
+		if v := query.Get("documentId"); v != "" {
+			idFilter, err := documentFilter(v)
+			if checkError(err, w, r) {
+				return
+			}
+			filter["documentId"] = idFilter[documentIDField]
+		}
+		data, err := client.AuditRecords(filter, limit)
+		if checkError(err, w, r) {
+			return
+		}
+		writeResponse(w, r, data)
+	}
+}
@@ -4,9 +4,10 @@ import (
 	"context"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/event"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
-	"go.mongodb.org/mongo-driver/mongo/readpref"
+	"sync"
 	"time"
 )
 
@@ -23,12 +24,19 @@ const (
 type Backend interface{}
 
 type MongoClient struct {
-	client        *mongo.Client
-	config        *MongoConfig
-	databaseLimit []string
+	client         *mongo.Client
+	config         *MongoConfig
+	databaseLimit  []string
+	ctx            context.Context
+	slowQueries    *slowQueryLog
+	audit          *auditLog
+	purger         *purger
+	hooks          *hookRegistry
+	webhooks       *Webhooks
+	historyIndexes *sync.Map
 }
 
-func NewMongoClient(conf *MongoConfig) (*MongoClient, error) {
+func NewMongoClient(conf *MongoConfig) (_ *MongoClient, err error) {
 	if conf == nil {
 		conf = DefaultMongoConfig()
 	}
@@ -36,19 +44,92 @@ func NewMongoClient(conf *MongoConfig) (*MongoClient, error) {
 	if conf.MongoUser != "" {
 		cred = &options.Credential{Username: conf.MongoUser, Password: conf.MongoPassword}
 	}
-	client, err := getClient(conf.MongoUri, cred)
+	var opts []*options.ClientOptions
+	if conf.Encryption != nil {
+		encryptionOptions, err := conf.Encryption.autoEncryptionOptions()
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, options.Client().SetAutoEncryptionOptions(encryptionOptions))
+	}
+	mongoClient := &MongoClient{
+		config:         conf,
+		databaseLimit:  conf.databaseLimit,
+		hooks:          newHookRegistry(),
+		historyIndexes: &sync.Map{},
+	}
+	var monitors []*event.CommandMonitor
+	if conf.Metrics {
+		registerMetrics()
+		monitors = append(monitors, newMetricsCommandMonitor())
+		opts = append(opts, options.Client().SetPoolMonitor(newMetricsPoolMonitor()))
+	}
+	if conf.Tracing {
+		monitors = append(monitors, newTracingCommandMonitor())
+	}
+	if conf.SlowQuery != nil {
+		mongoClient.slowQueries = newSlowQueryLog(*conf.SlowQuery, mongoClient.logger())
+		monitors = append(monitors, mongoClient.slowQueries.monitor())
+	}
+	if len(monitors) > 0 {
+		opts = append(opts, options.Client().SetMonitor(combineCommandMonitors(monitors)))
+	}
+	client, err := getClient(conf.MongoUri, cred, opts...)
 	if err != nil {
 		return nil, err
 	}
+	mongoClient.client = client
+	// stop the workers started so far and disconnect if the client is not returned
+	defer func() {
+		if err != nil {
+			mongoClient.Close()
+		}
+	}()
 	err = client.Ping(Ctx(), nil)
 	if err != nil {
 		return nil, err
 	}
-	return &MongoClient{
-		config:        conf,
-		client:        client,
-		databaseLimit: conf.databaseLimit,
-	}, nil
+	if conf.Audit != nil {
+		mongoClient.audit = newAuditLog(*conf.Audit, client)
+	}
+	if mongoClient.slowQueries != nil {
+		err = mongoClient.slowQueries.init(client)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if conf.Webhooks != nil {
+		mongoClient.webhooks, err = newMongoWebhooks(*conf.Webhooks, client, mongoClient.logger())
+		if err != nil {
+			return nil, err
+		}
+	}
+	if conf.SoftDelete != nil && conf.SoftDelete.Retention > 0 {
+		mongoClient.purger = newPurger(*mongoClient, *conf.SoftDelete)
+		go mongoClient.purger.run()
+	}
+	return mongoClient, nil
+}
+
+// WithContext returns a copy of the client which runs all operations with ctx, e.g. the
+// context of an HTTP request so operations are cancelled and traced along with it.
+func (b MongoClient) WithContext(ctx context.Context) *MongoClient {
+	b.ctx = ctx
+	return &b
+}
+
+func (b MongoClient) Context() context.Context {
+	if b.ctx == nil {
+		return Ctx()
+	}
+	return b.ctx
+}
+
+func (b MongoClient) logger() Logger {
+	if b.config.Logger != nil {
+		return b.config.Logger
+	}
+	return defaultLogger()
 }
 
 func (b MongoClient) Client() *mongo.Client {
@@ -56,18 +137,24 @@ func (b MongoClient) Client() *mongo.Client {
 }
 
 func (b MongoClient) Close() error {
+	if b.purger != nil {
+		b.purger.close()
+	}
+	if b.slowQueries != nil {
+		b.slowQueries.close()
+	}
+	if b.webhooks != nil {
+		b.webhooks.Close()
+	}
 	return b.client.Disconnect(Ctx())
 }
 
-func getClient(uri string, credentials *options.Credential) (*mongo.Client, error) {
-	var client *mongo.Client
-	var err error
-
+func getClient(uri string, credentials *options.Credential, opts ...*options.ClientOptions) (*mongo.Client, error) {
+	clientOptions := options.Client().ApplyURI(uri)
 	if credentials != nil {
-		client, err = mongo.NewClient(options.Client().ApplyURI(uri).SetAuth(*credentials))
-	} else {
-		client, err = mongo.NewClient(options.Client().ApplyURI(uri))
+		clientOptions.SetAuth(*credentials)
 	}
+	client, err := mongo.NewClient(append([]*options.ClientOptions{clientOptions}, opts...)...)
 	if err != nil {
 		return nil, err
 	}
@@ -77,19 +164,8 @@ func getClient(uri string, credentials *options.Credential) (*mongo.Client, erro
 func connect(client *mongo.Client) error {
 	return client.Connect(Ctx())
 }
-func (s MongoClient) ping() error {
-	ctx, cancelFunc := context.WithTimeout(context.Background(), 5*time.Second)
-	defer ctx.Done()
-	err := s.client.Ping(ctx, readpref.Primary())
-	if err != nil {
-		cancelFunc()
-		return err
-	}
-	cancelFunc = nil
-	return nil
-}
-func _getDatabases(client *mongo.Client) (mongo.ListDatabasesResult, error) {
-	result, err := client.ListDatabases(Ctx(), bson.M{})
+func _getDatabases(ctx context.Context, client *mongo.Client) (mongo.ListDatabasesResult, error) {
+	result, err := client.ListDatabases(ctx, bson.M{})
 	return result, err
 }
 
@@ -0,0 +1,109 @@
+package mongo
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"go.mongodb.org/mongo-driver/bson"
+	"io"
+	"net/http"
+)
+
+const bulkBatchSize = 1000
+
+var errNoArray = errors.New("request body must be a JSON array of documents")
+
+// BulkInsert decodes a JSON array of documents incrementally and inserts them in batches,
+// so the request body is never held in memory as a whole.
+func BulkInsert(mongoClient *MongoClient) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		client := mongoClient.WithContext(r.Context())
+		vars := mux.Vars(r)
+		database := vars["database"]
+		collection := vars["collection"]
+		if check(func() bool { return collection == "" || database == "" }, w) {
+			return
+		}
+		insertedIDs := make([]interface{}, 0)
+		err := decodeDocuments(r, bulkBatchSize, func(docs []interface{}) error {
+			ids, err := client.InsertMany(database, collection, docs)
+			insertedIDs = append(insertedIDs, ids...)
+			return err
+		})
+		if checkError(err, w, r) {
+			return
+		}
+		writeResponse(w, r, bson.M{
+			"insertedCount": len(insertedIDs),
+			"insertedIds":   insertedIDs,
+		})
+	}
+}
+
+// elementReader fails once an element has read more than maxDocumentSize bytes from the body,
+// so an oversized document is rejected before the decoder has buffered all of it.
+type elementReader struct {
+	r         io.Reader
+	remaining int64
+}
+
+func (e *elementReader) Read(p []byte) (int, error) {
+	if e.remaining <= 0 {
+		return 0, errDocumentTooLarge
+	}
+	if int64(len(p)) > e.remaining {
+		p = p[:e.remaining]
+	}
+	n, err := e.r.Read(p)
+	e.remaining -= int64(n)
+	return n, err
+}
+
+func decodeDocuments(r *http.Request, batchSize int, fn func(docs []interface{}) error) error {
+	body := &elementReader{r: r.Body, remaining: maxDocumentSize}
+	dec := json.NewDecoder(body)
+	token, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if delim, ok := token.(json.Delim); !ok || delim != '[' {
+		return errNoArray
+	}
+	batch := make([]interface{}, 0, batchSize)
+	for {
+		// the budget also covers the separators read ahead of each element
+		body.remaining = maxDocumentSize + 1
+		if !dec.More() {
+			break
+		}
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err != nil {
+			return err
+		}
+		if len(raw) > maxDocumentSize {
+			return errDocumentTooLarge
+		}
+		doc := bson.M{}
+		if err := bson.UnmarshalExtJSON(raw, true, &doc); err != nil {
+			return err
+		}
+		if _, ok := doc[documentIDField]; !ok {
+			doc[documentIDField] = uuid.New().String()
+		}
+		batch = append(batch, doc)
+		if len(batch) == batchSize {
+			if err := fn(batch); err != nil {
+				return err
+			}
+			batch = make([]interface{}, 0, batchSize)
+		}
+	}
+	if _, err := dec.Token(); err != nil {
+		return err
+	}
+	if len(batch) > 0 {
+		return fn(batch)
+	}
+	return nil
+}
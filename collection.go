@@ -27,12 +27,12 @@ func (b MongoClient) GetCollections(database string, nameOnly bool) (interface{}
 	if err != nil {
 		return nil, err
 	}
-	cursor, err := db.ListCollections(Ctx(), bson.M{}, &options.ListCollectionsOptions{NameOnly: proto.Bool(nameOnly)})
-	if !cursor.Next(Ctx()) {
-		return nil, nil
+	cursor, err := db.ListCollections(b.Context(), bson.M{}, &options.ListCollectionsOptions{NameOnly: proto.Bool(nameOnly)})
+	if err != nil {
+		return nil, err
 	}
-	var result []interface{}
-	err = cursor.All(Ctx(), &result)
+	result := make([]interface{}, 0)
+	err = cursor.All(b.Context(), &result)
 	return result, err
 }
 
@@ -41,5 +41,9 @@ func (b MongoClient) DropCollection(database string, collection string) error {
 	if err != nil {
 		return err
 	}
-	return col.Drop(Ctx())
+	err = col.Drop(b.Context())
+	if err == nil {
+		b.auditRecord(AuditDropCollection, database, collection, nil, nil, nil)
+	}
+	return err
 }
@@ -10,6 +10,16 @@ type MongoConfig struct {
 	MongoPassword     string
 	MongoUri          string
 	Timeout           time.Duration
+	Encryption        *EncryptionConfig
+	Metrics           bool
+	Tracing           bool
+	Logger            Logger
+	SlowQuery         *SlowQueryConfig
+	Audit             *AuditConfig
+	History           *HistoryConfig
+	SoftDelete        *SoftDeleteConfig
+	Metadata          *MetadataConfig
+	Webhooks          *WebhookConfig
 	databaseLimit     []string
 	databaseOptions   *options.DatabaseOptions
 	collectionOptions *options.CollectionOptions
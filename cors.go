@@ -0,0 +1,36 @@
+package mongo
+
+import (
+	"github.com/rs/cors"
+)
+
+// CorsConfig configures the CORS policy of the RestServer. Origins may contain a single
+// wildcard to allow subdomains, e.g. "https://*.example.com".
+type CorsConfig struct {
+	AllowedOrigins   []string
+	AllowedMethods   []string
+	AllowedHeaders   []string
+	ExposedHeaders   []string
+	MaxAge           int
+	AllowCredentials bool
+}
+
+func DefaultCorsConfig() *CorsConfig {
+	return &CorsConfig{
+		AllowedOrigins:   []string{"http://localhost:8081"},
+		AllowedMethods:   []string{"GET", "PUT", "POST", "PATCH", "DELETE"},
+		ExposedHeaders:   []string{"ETag", totalCountHeader},
+		AllowCredentials: true,
+	}
+}
+
+func (c *CorsConfig) options() cors.Options {
+	return cors.Options{
+		AllowedOrigins:   c.AllowedOrigins,
+		AllowedMethods:   c.AllowedMethods,
+		AllowedHeaders:   c.AllowedHeaders,
+		ExposedHeaders:   c.ExposedHeaders,
+		MaxAge:           c.MaxAge,
+		AllowCredentials: c.AllowCredentials,
+	}
+}
@@ -0,0 +1,35 @@
+package mongo
+
+import (
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func (b MongoClient) Count(database string, collection string, filter bson.M) (int64, error) {
+	col, err := b.GetCollection(database, collection, b.config.databaseOptions, b.config.collectionOptions)
+	if err != nil {
+		return 0, err
+	}
+	return col.CountDocuments(b.Context(), b.visible(database, collection, filter), options.Count())
+}
+
+// EstimatedCount returns the document count from the collection metadata without a scan.
+// Collections with soft deletes are counted exactly, the metadata includes deleted documents.
+func (b MongoClient) EstimatedCount(database string, collection string) (int64, error) {
+	if b.softDeletes(database, collection) {
+		return b.Count(database, collection, bson.M{})
+	}
+	col, err := b.GetCollection(database, collection, b.config.databaseOptions, b.config.collectionOptions)
+	if err != nil {
+		return 0, err
+	}
+	return col.EstimatedDocumentCount(b.Context(), options.EstimatedDocumentCount())
+}
+
+func (b MongoClient) Distinct(database string, collection string, field string, filter bson.M) ([]interface{}, error) {
+	col, err := b.GetCollection(database, collection, b.config.databaseOptions, b.config.collectionOptions)
+	if err != nil {
+		return nil, err
+	}
+	return col.Distinct(b.Context(), field, b.visible(database, collection, filter), options.Distinct())
+}
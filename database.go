@@ -20,7 +20,7 @@ func (b MongoClient) GetDatabases(databaseOptions *options.DatabaseOptions, name
 	if b.client == nil {
 		return nil, errors.New("Mongo client must not be nil")
 	}
-	res, err := _getDatabases(b.client)
+	res, err := _getDatabases(b.Context(), b.client)
 	return res.Databases, err
 }
 
@@ -29,5 +29,9 @@ func (b MongoClient) DropDatabase(database string) error {
 	if err != nil {
 		return err
 	}
-	return db.Drop(Ctx())
+	err = db.Drop(b.Context())
+	if err == nil {
+		b.auditRecord(AuditDropDatabase, database, "", nil, nil, nil)
+	}
+	return err
 }
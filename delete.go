@@ -1,6 +1,7 @@
 package mongo
 
 import (
+	"errors"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
@@ -11,8 +12,23 @@ func (b MongoClient) DeleteOne(database string, collection string, filter bson.M
 	if err != nil {
 		return err
 	}
-	_, err = DeleteOne(col, filter, &options.DeleteOptions{})
-	return err
+	if err := b.beforeDelete(database, collection, filter); err != nil {
+		return err
+	}
+	if b.softDeletes(database, collection) {
+		return b.softDelete(col, database, collection, filter)
+	}
+	var before bson.M
+	err = col.FindOneAndDelete(b.Context(), filter).Decode(&before)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	b.saveRevision(AuditDelete, database, collection, before)
+	b.auditRecord(AuditDelete, database, collection, before[documentIDField], before, nil)
+	return nil
 }
 
 func DeleteOne(collection *mongo.Collection, filter bson.M, deleteOptions *options.DeleteOptions) (*mongo.DeleteResult, error) {
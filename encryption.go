@@ -0,0 +1,163 @@
+package mongo
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"io/ioutil"
+	"strings"
+)
+
+const (
+	localKmsProvider         = "local"
+	localMasterKeySize       = 96
+	defaultKeyVaultNamespace = "encryption.__keyVault"
+
+	AlgorithmDeterministic = "AEAD_AES_256_CBC_HMAC_SHA_512-Deterministic"
+	AlgorithmRandom        = "AEAD_AES_256_CBC_HMAC_SHA_512-Random"
+)
+
+// EncryptionConfig enables client-side field level encryption with the local KMS provider.
+// SchemaMap is keyed by namespace ("database.collection") and holds a $jsonSchema document,
+// see EncryptionSchema. The driver has to be built with the "cse" tag (libmongocrypt) and
+// mongocryptd must be reachable for automatic encryption to work.
+type EncryptionConfig struct {
+	KeyVaultNamespace    string
+	LocalMasterKeyFile   string
+	SchemaMap            map[string]interface{}
+	BypassAutoEncryption bool
+}
+
+func (c *EncryptionConfig) keyVaultNamespace() string {
+	if c.KeyVaultNamespace == "" {
+		return defaultKeyVaultNamespace
+	}
+	return c.KeyVaultNamespace
+}
+
+func (c *EncryptionConfig) kmsProviders() (map[string]map[string]interface{}, error) {
+	key, err := readLocalMasterKey(c.LocalMasterKeyFile)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]map[string]interface{}{
+		localKmsProvider: {"key": key},
+	}, nil
+}
+
+func (c *EncryptionConfig) autoEncryptionOptions() (*options.AutoEncryptionOptions, error) {
+	providers, err := c.kmsProviders()
+	if err != nil {
+		return nil, err
+	}
+	opts := options.AutoEncryption().
+		SetKeyVaultNamespace(c.keyVaultNamespace()).
+		SetKmsProviders(providers).
+		SetBypassAutoEncryption(c.BypassAutoEncryption)
+	if c.SchemaMap != nil {
+		opts.SetSchemaMap(c.SchemaMap)
+	}
+	return opts, nil
+}
+
+// readLocalMasterKey accepts either the raw 96 byte key or its base64 encoding.
+func readLocalMasterKey(file string) ([]byte, error) {
+	if file == "" {
+		return nil, errors.New("local master key file must not be empty")
+	}
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) == localMasterKeySize {
+		return data, nil
+	}
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(data)))
+	if err != nil || len(key) != localMasterKeySize {
+		return nil, fmt.Errorf("local master key in %s must be %d bytes", file, localMasterKeySize)
+	}
+	return key, nil
+}
+
+// EncryptedField describes a single encrypted field of an EncryptionSchema. Deterministic
+// encryption keeps the field queryable by equality, random encryption is stronger but the
+// field can not be used in filters.
+func EncryptedField(keyID primitive.Binary, bsonType string, deterministic bool) bson.M {
+	algorithm := AlgorithmRandom
+	if deterministic {
+		algorithm = AlgorithmDeterministic
+	}
+	encrypt := bson.M{
+		"keyId":     bson.A{keyID},
+		"algorithm": algorithm,
+	}
+	if bsonType != "" {
+		encrypt["bsonType"] = bsonType
+	}
+	return bson.M{"encrypt": encrypt}
+}
+
+// EncryptionSchema builds a $jsonSchema document from encrypted fields keyed by their
+// (dotted) path, suitable as a value of EncryptionConfig.SchemaMap. A path must not be empty
+// or contain another one, e.g. "a" and "a.b", as encrypted fields can not have properties.
+func EncryptionSchema(fields map[string]bson.M) (bson.M, error) {
+	for path := range fields {
+		parts := strings.Split(path, ".")
+		for i, part := range parts {
+			if part == "" {
+				return nil, fmt.Errorf("invalid encrypted field path %q", path)
+			}
+			parent := strings.Join(parts[:i+1], ".")
+			if _, ok := fields[parent]; ok && parent != path {
+				return nil, fmt.Errorf("encrypted field paths %q and %q overlap", parent, path)
+			}
+		}
+	}
+	schema := bson.M{"bsonType": "object", "properties": bson.M{}}
+	for path := range fields {
+		properties := schema["properties"].(bson.M)
+		parts := strings.Split(path, ".")
+		for _, part := range parts[:len(parts)-1] {
+			child, ok := properties[part].(bson.M)
+			if !ok {
+				child = bson.M{"bsonType": "object", "properties": bson.M{}}
+				properties[part] = child
+			}
+			properties = child["properties"].(bson.M)
+		}
+		properties[parts[len(parts)-1]] = fields[path]
+	}
+	return schema, nil
+}
+
+// CreateDataKey creates a data key in the key vault, encrypted by the local master key.
+func (b MongoClient) CreateDataKey(keyAltNames ...string) (primitive.Binary, error) {
+	if b.config.Encryption == nil {
+		return primitive.Binary{}, errors.New("encryption is not configured")
+	}
+	ce, err := b.clientEncryption()
+	if err != nil {
+		return primitive.Binary{}, err
+	}
+	defer ce.Close(b.Context())
+	opts := options.DataKey()
+	if len(keyAltNames) > 0 {
+		opts.SetKeyAltNames(keyAltNames)
+	}
+	return ce.CreateDataKey(b.Context(), localKmsProvider, opts)
+}
+
+func (b MongoClient) clientEncryption() (*mongo.ClientEncryption, error) {
+	providers, err := b.config.Encryption.kmsProviders()
+	if err != nil {
+		return nil, err
+	}
+	opts := options.ClientEncryption().
+		SetKeyVaultNamespace(b.config.Encryption.keyVaultNamespace()).
+		SetKmsProviders(providers)
+	return mongo.NewClientEncryption(b.client, opts)
+}
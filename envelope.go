@@ -0,0 +1,153 @@
+package mongo
+
+import (
+	"context"
+	"go.mongodb.org/mongo-driver/bson"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Response envelopes, selected by ServerConfig.Envelope:
+//
+//	body     {"body": data} (default)
+//	metadata {"data": data, "count", "total", "next", "prev", "took", "warnings"}
+//	none     data without an envelope
+//
+// The envelope is used by the JSON formats, BSON lists, CSV and NDJSON carry the data only.
+// Health probes are wrapped like every other response.
+const (
+	EnvelopeBody     = "body"
+	EnvelopeMetadata = "metadata"
+	EnvelopeNone     = "none"
+)
+
+// responseMeta collects the metadata of a response while the request is handled.
+type responseMeta struct {
+	mu       sync.Mutex
+	envelope string
+	start    time.Time
+	total    *int64
+	warnings []string
+}
+
+func withResponseMeta(ctx context.Context, envelope string) context.Context {
+	if envelope == "" {
+		envelope = EnvelopeBody
+	}
+	return context.WithValue(ctx, responseMetaKey, &responseMeta{envelope: envelope, start: time.Now()})
+}
+
+func responseMetaFromContext(ctx context.Context) *responseMeta {
+	if meta, ok := ctx.Value(responseMetaKey).(*responseMeta); ok {
+		return meta
+	}
+	return &responseMeta{envelope: EnvelopeBody, start: time.Now()}
+}
+
+// AddWarning adds a warning to the metadata envelope of the response to the request of ctx.
+func AddWarning(ctx context.Context, warning string) {
+	meta := responseMetaFromContext(ctx)
+	meta.mu.Lock()
+	defer meta.mu.Unlock()
+	meta.warnings = append(meta.warnings, warning)
+}
+
+func setTotal(ctx context.Context, total int64) {
+	meta := responseMetaFromContext(ctx)
+	meta.mu.Lock()
+	defer meta.mu.Unlock()
+	meta.total = &total
+}
+
+// wrap returns the envelope of a single document.
+func (m *responseMeta) wrap(r *http.Request, data interface{}) interface{} {
+	switch m.envelope {
+	case EnvelopeNone:
+		return data
+	case EnvelopeMetadata:
+		return append(bson.D{{Key: "data", Value: data}}, m.fields(r, 1)...)
+	}
+	return bson.D{{Key: "body", Value: data}}
+}
+
+// listOpen and listClose enclose the items of a JSON list.
+func (m *responseMeta) listOpen() string {
+	switch m.envelope {
+	case EnvelopeNone:
+		return "["
+	case EnvelopeMetadata:
+		return `{"data":[`
+	}
+	return `{"body":[`
+}
+
+func (m *responseMeta) listClose(f format, r *http.Request, count int) ([]byte, error) {
+	switch m.envelope {
+	case EnvelopeNone:
+		return []byte("]"), nil
+	case EnvelopeMetadata:
+		fields, err := f.marshalJSON(m.fields(r, count))
+		if err != nil {
+			return nil, err
+		}
+		// the metadata follows the data, as count is only known after the last item
+		return append([]byte("],"), fields[1:]...), nil
+	}
+	return []byte("]}"), nil
+}
+
+// fields returns the metadata of a response with count items. Without limit and skip the
+// total is the count, next and prev are the request URL with the skip of the adjacent pages.
+func (m *responseMeta) fields(r *http.Request, count int) bson.D {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	query := r.URL.Query()
+	limit, _ := strconv.ParseInt(query.Get(limitParam), 10, 64)
+	skip, _ := strconv.ParseInt(query.Get(skipParam), 10, 64)
+	var total, next, prev interface{}
+	switch {
+	case m.total != nil:
+		total = *m.total
+	case limit <= 0 && skip <= 0:
+		total = int64(count)
+	}
+	if limit > 0 && int64(count) == limit && (m.total == nil || skip+limit < *m.total) {
+		next = pageURL(r, skip+limit)
+	}
+	if skip > 0 {
+		prevSkip := skip - limit
+		if limit <= 0 || prevSkip < 0 {
+			prevSkip = 0
+		}
+		prev = pageURL(r, prevSkip)
+	}
+	warnings := make([]string, len(m.warnings))
+	copy(warnings, m.warnings)
+	return bson.D{
+		{Key: "count", Value: count},
+		{Key: "total", Value: total},
+		{Key: "next", Value: next},
+		{Key: "prev", Value: prev},
+		{Key: "took", Value: float64(time.Since(m.start).Microseconds()) / 1000},
+		{Key: "warnings", Value: warnings},
+	}
+}
+
+// pageURL returns the request URI, including a stripped path prefix, with skip replaced.
+func pageURL(r *http.Request, skip int64) string {
+	u, err := url.ParseRequestURI(r.RequestURI)
+	if err != nil {
+		u = r.URL
+	}
+	query := u.Query()
+	if skip > 0 {
+		query.Set(skipParam, strconv.FormatInt(skip, 10))
+	} else {
+		query.Del(skipParam)
+	}
+	result := url.URL{Path: u.Path, RawQuery: query.Encode()}
+	return result.String()
+}
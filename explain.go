@@ -0,0 +1,181 @@
+package mongo
+
+import (
+	"context"
+	"fmt"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"net/http"
+)
+
+const (
+	VerbosityQueryPlanner      = "queryPlanner"
+	VerbosityExecutionStats    = "executionStats"
+	VerbosityAllPlansExecution = "allPlansExecution"
+
+	explainParam = "explain"
+)
+
+type ExplainResult struct {
+	Verbosity       string      `bson:"verbosity"`
+	WinningPlan     interface{} `bson:"winningPlan,omitempty"`
+	Stages          []string    `bson:"stages"`
+	Indexes         []string    `bson:"indexes"`
+	CollScan        bool        `bson:"collScan"`
+	DocsExamined    int64       `bson:"docsExamined"`
+	KeysExamined    int64       `bson:"keysExamined"`
+	DocsReturned    int64       `bson:"docsReturned"`
+	ExecutionTimeMs int64       `bson:"executionTimeMs"`
+}
+
+// Explain explains a find with the filter or, if query is a pipeline, an aggregation.
+// Execution statistics are only set for the executionStats and allPlansExecution verbosity.
+func (b MongoClient) Explain(database string, collection string, query interface{}, verbosity string) (*ExplainResult, error) {
+	if verbosity == "" {
+		verbosity = VerbosityQueryPlanner
+	}
+	if verbosity != VerbosityQueryPlanner && verbosity != VerbosityExecutionStats && verbosity != VerbosityAllPlansExecution {
+		return nil, fmt.Errorf("unknown explain verbosity %s", verbosity)
+	}
+	db, err := b.GetDatabase(database, b.config.databaseOptions)
+	if err != nil {
+		return nil, err
+	}
+	var cmd bson.D
+	if isPipeline(query) {
+		query = b.visiblePipeline(database, collection, query)
+		cmd = bson.D{{Key: "aggregate", Value: collection}, {Key: "pipeline", Value: query}, {Key: "cursor", Value: bson.M{}}}
+	} else {
+		if query == nil {
+			query = bson.M{}
+		}
+		if filter, ok := query.(bson.M); ok {
+			query = b.visible(database, collection, filter)
+		}
+		cmd = bson.D{{Key: "find", Value: collection}, {Key: "filter", Value: query}}
+	}
+	explain, err := explainCommand(b.Context(), db, cmd, verbosity)
+	if err != nil {
+		return nil, err
+	}
+	return newExplainResult(explain, verbosity), nil
+}
+
+func isPipeline(query interface{}) bool {
+	switch query.(type) {
+	case bson.A, []interface{}, []bson.M, []bson.D, mongo.Pipeline:
+		return true
+	}
+	return false
+}
+
+func explainCommand(ctx context.Context, db *mongo.Database, cmd bson.D, verbosity string) (bson.M, error) {
+	var result bson.M
+	err := db.RunCommand(ctx, bson.D{{Key: "explain", Value: cmd}, {Key: "verbosity", Value: verbosity}}).Decode(&result)
+	return result, err
+}
+
+func newExplainResult(explain bson.M, verbosity string) *ExplainResult {
+	result := &ExplainResult{
+		Verbosity:   verbosity,
+		WinningPlan: findKey(explain, "winningPlan"),
+		Stages:      planStages(explain),
+		Indexes:     make([]string, 0),
+	}
+	result.CollScan = containsString(result.Stages, "COLLSCAN")
+	walkPlans(explain, func(stage bson.M) {
+		if index, ok := stage["indexName"].(string); ok && !containsString(result.Indexes, index) {
+			result.Indexes = append(result.Indexes, index)
+		}
+	})
+	if stats, ok := findKey(explain, "executionStats").(bson.M); ok {
+		result.DocsExamined = toInt64(stats["totalDocsExamined"])
+		result.KeysExamined = toInt64(stats["totalKeysExamined"])
+		result.DocsReturned = toInt64(stats["nReturned"])
+		result.ExecutionTimeMs = toInt64(stats["executionTimeMillis"])
+	}
+	return result
+}
+
+// findKey returns the value of the first element named key in a depth first walk.
+func findKey(v interface{}, key string) interface{} {
+	switch value := v.(type) {
+	case bson.M:
+		if found, ok := value[key]; ok {
+			return found
+		}
+		for _, child := range value {
+			if found := findKey(child, key); found != nil {
+				return found
+			}
+		}
+	case bson.D:
+		return findKey(value.Map(), key)
+	case bson.A:
+		for _, child := range value {
+			if found := findKey(child, key); found != nil {
+				return found
+			}
+		}
+	}
+	return nil
+}
+
+// walkPlans calls fn for every stage of all winning plans in an explain result.
+func walkPlans(explain interface{}, fn func(stage bson.M)) {
+	var walk func(v interface{}, inPlan bool)
+	walk = func(v interface{}, inPlan bool) {
+		switch value := v.(type) {
+		case bson.M:
+			if _, ok := value["stage"].(string); ok && inPlan {
+				fn(value)
+			}
+			for k, child := range value {
+				walk(child, inPlan || k == "winningPlan")
+			}
+		case bson.D:
+			walk(value.Map(), inPlan)
+		case bson.A:
+			for _, child := range value {
+				walk(child, inPlan)
+			}
+		}
+	}
+	walk(explain, false)
+}
+
+// planStages collects the stage names of all winning plans in an explain result.
+func planStages(explain interface{}) []string {
+	stages := make([]string, 0)
+	walkPlans(explain, func(stage bson.M) {
+		stages = append(stages, stage["stage"].(string))
+	})
+	return stages
+}
+
+func toInt64(v interface{}) int64 {
+	switch value := v.(type) {
+	case int32:
+		return int64(value)
+	case int64:
+		return value
+	case float64:
+		return int64(value)
+	}
+	return 0
+}
+
+// writeExplain answers a request with ?explain=<verbosity> with the explain result of query
+// and reports whether it did so.
+func writeExplain(client *MongoClient, database, collection string, query interface{}, w http.ResponseWriter, r *http.Request) bool {
+	verbosity, ok := r.URL.Query()[explainParam]
+	if !ok {
+		return false
+	}
+	data, err := client.Explain(database, collection, query, verbosity[0])
+	if checkError(err, w, r) {
+		return true
+	}
+	writeResponse(w, r, data)
+	return true
+}
@@ -1,6 +1,7 @@
 package mongo
 
 import (
+	"context"
 	"errors"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
@@ -13,40 +14,55 @@ func (b MongoClient) FindOne(database string, collection string, filter bson.M)
 		return nil, err
 	}
 
-	return FindOne(col, filter)
+	doc, err := findOne(b.Context(), col, b.visible(database, collection, filter))
+	if err != nil {
+		return nil, err
+	}
+	if hook := b.afterFind(database, collection); hook != nil {
+		err = hook(doc)
+	}
+	return doc, err
 }
 
-func (b MongoClient) FindMany(database string, collection string, filter bson.M) ([]bson.M, error) {
+func (b MongoClient) FindMany(database string, collection string, filter bson.M, opts ...*options.FindOptions) ([]bson.M, error) {
 	col, err := b.GetCollection(database, collection, b.config.databaseOptions, b.config.collectionOptions)
 	if err != nil {
 		return nil, err
 	}
-	cursor, err := FindMany(col, filter)
+	cursor, err := findMany(b.Context(), col, b.visible(database, collection, filter), opts...)
 	if err != nil {
 		return nil, err
 	}
 	result := make([]bson.M, 0)
-	if cursor.Next(Ctx()) {
-		err = cursor.All(Ctx(), &result)
-		return result, err
+	err = cursor.All(b.Context(), &result)
+	if err != nil || len(result) == 0 {
+		return nil, err
 	}
-	return nil, nil
+	return result, b.afterFindAll(database, collection, result)
 }
-func (b MongoClient) FindAll(database string, collection string) (interface{}, error) {
+func (b MongoClient) FindAll(database string, collection string, opts ...*options.FindOptions) (interface{}, error) {
 	col, err := b.GetCollection(database, collection, b.config.databaseOptions, b.config.collectionOptions)
 	if err != nil {
 		return nil, err
 	}
-	cursor, err := FindAll(col)
+	cursor, err := findMany(b.Context(), col, b.visible(database, collection, bson.M{}), opts...)
 	if err != nil {
 		return nil, err
 	}
+	if b.afterFind(database, collection) != nil {
+		result := make([]bson.M, 0)
+		err = cursor.All(b.Context(), &result)
+		if err != nil || len(result) == 0 {
+			return nil, err
+		}
+		return result, b.afterFindAll(database, collection, result)
+	}
 	result := make([]interface{}, 0)
-	if cursor.Next(Ctx()) {
-		err = cursor.All(Ctx(), &result)
-		return result, err
+	err = cursor.All(b.Context(), &result)
+	if err != nil || len(result) == 0 {
+		return nil, err
 	}
-	return nil, nil
+	return result, nil
 }
 
 func FindAll(collection *mongo.Collection) (*mongo.Cursor, error) {
@@ -54,10 +70,19 @@ func FindAll(collection *mongo.Collection) (*mongo.Cursor, error) {
 }
 
 func FindMany(collection *mongo.Collection, filter bson.M) (*mongo.Cursor, error) {
-	return collection.Find(Ctx(), filter, &options.FindOptions{})
+	return findMany(Ctx(), collection, filter)
+}
+
+func findMany(ctx context.Context, collection *mongo.Collection, filter bson.M, opts ...*options.FindOptions) (*mongo.Cursor, error) {
+	return collection.Find(ctx, filter, opts...)
 }
+
 func FindOne(collection *mongo.Collection, filter bson.M) (bson.M, error) {
-	res := collection.FindOne(Ctx(), filter, &options.FindOneOptions{})
+	return findOne(Ctx(), collection, filter)
+}
+
+func findOne(ctx context.Context, collection *mongo.Collection, filter bson.M) (bson.M, error) {
+	res := collection.FindOne(ctx, filter, &options.FindOneOptions{})
 	if res == nil {
 		return nil, errors.New("Result must not be nil")
 	}
@@ -77,7 +102,11 @@ func FindOneAndDelete(collection *mongo.Collection, filter bson.M, deleteOptions
 }
 
 func FindOneAndReplace(collection *mongo.Collection, filter bson.M, replacement interface{}, options ...*options.FindOneAndReplaceOptions) error {
-	res := collection.FindOneAndReplace(Ctx(), filter, replacement, options...)
+	return findOneAndReplace(Ctx(), collection, filter, replacement, options...)
+}
+
+func findOneAndReplace(ctx context.Context, collection *mongo.Collection, filter bson.M, replacement interface{}, options ...*options.FindOneAndReplaceOptions) error {
+	res := collection.FindOneAndReplace(ctx, filter, replacement, options...)
 	if res == nil {
 		return errors.New("Result must not be nil")
 	}
@@ -89,7 +118,11 @@ func FindOneAndReplace(collection *mongo.Collection, filter bson.M, replacement
 }
 
 func FindOneAndUpdate(collection *mongo.Collection, filter bson.M, update bson.M) error {
-	res := collection.FindOneAndUpdate(Ctx(), filter, update, &options.FindOneAndUpdateOptions{})
+	return findOneAndUpdate(Ctx(), collection, filter, update)
+}
+
+func findOneAndUpdate(ctx context.Context, collection *mongo.Collection, filter bson.M, update bson.M) error {
+	res := collection.FindOneAndUpdate(ctx, filter, update, &options.FindOneAndUpdateOptions{})
 	if res == nil {
 		return errors.New("Result must not be nil")
 	}
@@ -0,0 +1,415 @@
+package mongo
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"io"
+	"math"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Response formats, selected by the Accept header or the format query parameter:
+//
+//	application/json                   relaxed extended JSON (default)
+//	application/json; format=canonical canonical extended JSON
+//	application/json; format=plain     plain JSON, ObjectIDs as hex and dates as RFC 3339 strings
+//	application/x-ndjson               one JSON document per line, accepts the format parameter
+//	application/bson                   BSON, lists are streamed as a sequence of documents
+//	text/csv                           CSV with the columns of the fields query parameter
+const (
+	FormatRelaxed   = "relaxed"
+	FormatCanonical = "canonical"
+	FormatPlain     = "plain"
+	FormatBSON      = "bson"
+	FormatCSV       = "csv"
+	FormatNDJSON    = "ndjson"
+
+	formatParam     = "format"
+	fieldsParam     = "fields"
+	bsonContentType = "application/bson"
+	csvContentType  = "text/csv"
+)
+
+type format struct {
+	name   string
+	ndjson bool
+	fields []string
+}
+
+func isJSONFormat(name string) bool {
+	return name == FormatRelaxed || name == FormatCanonical || name == FormatPlain
+}
+
+func negotiateFormat(r *http.Request) format {
+	f := format{name: FormatRelaxed}
+	query := r.URL.Query()
+	if fields := query.Get(fieldsParam); fields != "" {
+		f.fields = strings.Split(fields, ",")
+	}
+	if v := query.Get(formatParam); v != "" {
+		switch {
+		case v == FormatNDJSON:
+			f.ndjson = true
+		case isJSONFormat(v) || v == FormatBSON || v == FormatCSV:
+			f.name = v
+		}
+		return f
+	}
+	for _, accepted := range strings.Split(r.Header.Get("Accept"), ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(accepted))
+		if err != nil {
+			continue
+		}
+		switch mediaType {
+		case jsonContentType, ndjsonContentType, "application/*", "*/*":
+			f.ndjson = mediaType == ndjsonContentType
+			if isJSONFormat(params[formatParam]) {
+				f.name = params[formatParam]
+			}
+			return f
+		case bsonContentType:
+			f.name = FormatBSON
+			return f
+		case csvContentType:
+			f.name = FormatCSV
+			return f
+		}
+	}
+	return f
+}
+
+func (f format) contentType() string {
+	switch {
+	case f.name == FormatBSON:
+		return bsonContentType
+	case f.name == FormatCSV:
+		return csvContentType
+	case f.ndjson:
+		return ndjsonContentType
+	}
+	return jsonContentType
+}
+
+// marshalJSON encodes a document or a single value in one of the JSON formats.
+func (f format) marshalJSON(v interface{}) ([]byte, error) {
+	if f.name == FormatPlain {
+		normalized, err := normalize(v)
+		if err != nil {
+			return nil, err
+		}
+		return json.Marshal(plainValue(normalized))
+	}
+	canonical := f.name == FormatCanonical
+	switch v.(type) {
+	case bson.D, bson.M, bson.Raw:
+		return bson.MarshalExtJSON(v, canonical, false)
+	}
+	// extended JSON can only be written for documents, other values are cut out of one
+	data, err := bson.MarshalExtJSON(bson.D{{Key: "v", Value: v}}, canonical, false)
+	if err != nil {
+		return nil, err
+	}
+	return data[len(`{"v":`) : len(data)-1], nil
+}
+
+// writeResponse writes data in the format requested by the client wrapped into the
+// envelope configured for the server. Lists are written like streamed cursors, so both
+// look the same to clients. Nil is written as an empty list.
+func writeResponse(w http.ResponseWriter, r *http.Request, data interface{}) {
+	writeResponseStatus(w, r, http.StatusOK, data)
+}
+
+// writeResponseStatus is writeResponse with another status than 200.
+func writeResponseStatus(w http.ResponseWriter, r *http.Request, status int, data interface{}) {
+	f := negotiateFormat(r)
+	normalized, err := normalize(data)
+	if checkError(err, w, r) {
+		return
+	}
+	var buf bytes.Buffer
+	items, isList := normalized.(bson.A)
+	if isList || normalized == nil {
+		stream := newStreamWriter(f, &buf, r)
+		for _, item := range items {
+			if err = stream.write(item); err != nil {
+				break
+			}
+		}
+		if err == nil {
+			err = stream.finish()
+		}
+	} else {
+		err = writeDocument(f, &buf, r, normalized)
+	}
+	if checkError(err, w, r) {
+		return
+	}
+	w.Header().Set("Content-Type", f.contentType())
+	if status != http.StatusOK {
+		w.WriteHeader(status)
+	}
+	_, err = w.Write(buf.Bytes())
+	if checkError(err, w, r) {
+		return
+	}
+}
+
+func writeDocument(f format, w io.Writer, r *http.Request, doc interface{}) error {
+	if f.name == FormatCSV || f.ndjson {
+		stream := newStreamWriter(f, w, r)
+		if err := stream.write(doc); err != nil {
+			return err
+		}
+		return stream.finish()
+	}
+	var data []byte
+	var err error
+	wrapped := responseMetaFromContext(r.Context()).wrap(r, doc)
+	if f.name == FormatBSON {
+		data, err = bson.Marshal(asDocument(wrapped))
+	} else {
+		data, err = f.marshalJSON(wrapped)
+	}
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
+}
+
+// asDocument wraps values which are no documents as {"value": v}.
+func asDocument(v interface{}) interface{} {
+	switch v.(type) {
+	case bson.D, bson.M, bson.Raw:
+		return v
+	}
+	return bson.D{{Key: "value", Value: v}}
+}
+
+// streamWriter writes the items of a list one by one. JSON lists are enclosed by the
+// envelope which is opened with the first item, so errors before it can still be answered
+// with an error status.
+type streamWriter struct {
+	f       format
+	w       io.Writer
+	r       *http.Request
+	meta    *responseMeta
+	written int
+	csv     *csv.Writer
+	columns []string
+}
+
+func newStreamWriter(f format, w io.Writer, r *http.Request) *streamWriter {
+	return &streamWriter{f: f, w: w, r: r, meta: responseMetaFromContext(r.Context())}
+}
+
+func (s *streamWriter) write(item interface{}) error {
+	defer func() { s.written++ }()
+	switch {
+	case s.f.name == FormatBSON:
+		data, err := bson.Marshal(asDocument(item))
+		if err != nil {
+			return err
+		}
+		_, err = s.w.Write(data)
+		return err
+	case s.f.name == FormatCSV:
+		return s.writeRow(item)
+	}
+	data, err := s.f.marshalJSON(item)
+	if err != nil {
+		return err
+	}
+	if s.f.ndjson {
+		data = append(data, '\n')
+	} else if s.written == 0 {
+		data = append([]byte(s.meta.listOpen()), data...)
+	} else {
+		data = append([]byte(","), data...)
+	}
+	_, err = s.w.Write(data)
+	return err
+}
+
+func (s *streamWriter) writeRow(item interface{}) error {
+	normalized, err := normalize(item)
+	if err != nil {
+		return err
+	}
+	doc := asDocument(normalized).(bson.D)
+	values := map[string]interface{}{}
+	keys := flatten("", doc, values)
+	if s.csv == nil {
+		s.csv = csv.NewWriter(s.w)
+		s.columns = s.f.fields
+		if len(s.columns) == 0 {
+			s.columns = keys
+		}
+		if err := s.csv.Write(s.columns); err != nil {
+			return err
+		}
+	}
+	row := make([]string, len(s.columns))
+	for i, column := range s.columns {
+		row[i] = csvValue(values[column])
+	}
+	if err := s.csv.Write(row); err != nil {
+		return err
+	}
+	s.csv.Flush()
+	return s.csv.Error()
+}
+
+func (s *streamWriter) finish() error {
+	switch {
+	case s.f.name == FormatBSON, s.f.ndjson:
+		return nil
+	case s.f.name == FormatCSV:
+		if s.csv == nil && len(s.f.fields) > 0 {
+			s.csv = csv.NewWriter(s.w)
+			if err := s.csv.Write(s.f.fields); err != nil {
+				return err
+			}
+			s.csv.Flush()
+			return s.csv.Error()
+		}
+		return nil
+	}
+	var data []byte
+	if s.written == 0 {
+		data = []byte(s.meta.listOpen())
+	}
+	closing, err := s.meta.listClose(s.f, s.r, s.written)
+	if err != nil {
+		return err
+	}
+	_, err = s.w.Write(append(data, closing...))
+	return err
+}
+
+// flatten collects the values of a document by their dotted path and returns the paths
+// in document order.
+func flatten(prefix string, doc bson.D, values map[string]interface{}) []string {
+	keys := make([]string, 0, len(doc))
+	for _, e := range doc {
+		key := prefix + e.Key
+		if nested, ok := e.Value.(bson.D); ok {
+			keys = append(keys, flatten(key+".", nested, values)...)
+			continue
+		}
+		values[key] = e.Value
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+func csvValue(v interface{}) string {
+	switch value := plainValue(v).(type) {
+	case nil:
+		return ""
+	case string:
+		return value
+	case bool:
+		return strconv.FormatBool(value)
+	case int32, int64:
+		return fmt.Sprint(value)
+	case float64:
+		return strconv.FormatFloat(value, 'f', -1, 64)
+	default:
+		data, err := json.Marshal(value)
+		if err != nil {
+			return ""
+		}
+		return string(data)
+	}
+}
+
+// normalize converts any value BSON can encode, e.g. structs, bson.M or bson.Raw, into
+// bson.D, bson.A and primitive values.
+func normalize(v interface{}) (interface{}, error) {
+	data, err := bson.Marshal(bson.D{{Key: "v", Value: v}})
+	if err != nil {
+		return nil, err
+	}
+	var doc bson.D
+	if err := bson.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	return doc[0].Value, nil
+}
+
+// plainDoc keeps the element order of a document when encoded as plain JSON.
+type plainDoc bson.D
+
+func (d plainDoc) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, e := range d {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		key, err := json.Marshal(e.Key)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(key)
+		buf.WriteByte(':')
+		value, err := json.Marshal(e.Value)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// plainValue maps BSON types to their closest plain JSON representation.
+func plainValue(v interface{}) interface{} {
+	switch value := v.(type) {
+	case bson.D:
+		doc := make(plainDoc, 0, len(value))
+		for _, e := range value {
+			doc = append(doc, bson.E{Key: e.Key, Value: plainValue(e.Value)})
+		}
+		return doc
+	case bson.A:
+		list := make([]interface{}, 0, len(value))
+		for _, item := range value {
+			list = append(list, plainValue(item))
+		}
+		return list
+	case primitive.ObjectID:
+		return value.Hex()
+	case primitive.DateTime:
+		return time.Unix(0, int64(value)*int64(time.Millisecond)).UTC().Format(time.RFC3339Nano)
+	case primitive.Timestamp:
+		return time.Unix(int64(value.T), 0).UTC().Format(time.RFC3339)
+	case primitive.Decimal128:
+		return value.String()
+	case primitive.Binary:
+		return value.Data
+	case primitive.Regex:
+		return "/" + value.Pattern + "/" + value.Options
+	case primitive.JavaScript:
+		return string(value)
+	case primitive.Symbol:
+		return string(value)
+	case primitive.Null, primitive.Undefined, primitive.MinKey, primitive.MaxKey:
+		return nil
+	case float64:
+		if math.IsNaN(value) || math.IsInf(value, 0) {
+			return nil
+		}
+		return value
+	}
+	return v
+}
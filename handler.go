@@ -1,36 +1,93 @@
 package mongo
 
 import (
+	"bytes"
+	"context"
+	"errors"
 	"github.com/golang/protobuf/proto"
 	"github.com/google/uuid"
 	"github.com/gorilla/mux"
 	"go.mongodb.org/mongo-driver/bson"
-	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
+	"io"
 	"io/ioutil"
-	"log"
 	"net/http"
+	"net/url"
 	"strconv"
 	"strings"
 )
 
+const (
+	fullScanCost  = 10
+	bulkCost      = 10
+	aggregateCost = 10
+
+	limitParam       = "limit"
+	skipParam        = "skip"
+	estimatedParam   = "estimated"
+	totalCountHeader = "X-Total-Count"
+
+	// maxDocumentSize is the BSON document size limit of MongoDB
+	maxDocumentSize = 16 * 1024 * 1024
+)
+
+var (
+	errDocumentTooLarge = errors.New("document exceeds the maximum document size of 16MB")
+	errNoPipeline       = errors.New("request body must be a pipeline array or {\"pipeline\": [...]}")
+	errReadOnlyStage    = errors.New("$out and $merge stages are not allowed in read-only mode")
+
+	// reservedParams are query parameters which are not part of a search filter
+	reservedParams = map[string]bool{explainParam: true, limitParam: true, skipParam: true, estimatedParam: true, batchSizeParam: true,
+		formatParam: true, fieldsParam: true}
+)
+
 func GetRoutes(mongoClient *MongoClient) []Route {
+	routes := append(HealthRoutes(mongoClient), AdminRoutes(mongoClient)...)
+	return append(routes, NamespaceRoutes(mongoClient)...)
+}
+
+// NamespaceRoutes are the routes of databases, collections and documents. Their path
+// variables are decoded and validated before the handlers are called.
+func NamespaceRoutes(mongoClient *MongoClient) []Route {
+	return namespaceRoutes(mongoClient, DefaultSegments())
+}
+
+func namespaceRoutes(mongoClient *MongoClient, segments Segments) []Route {
+	collectionPath := "/" + databaseVar + "/" + collectionVar
+	documentPath := collectionPath + "/" + documentVar
+	revisionPath := documentPath + "/" + segments.History + "/{version:[0-9]+}"
 	routes := []Route{
-		{Path: "/{database:[a-z]+}/{collection:[a-z]+}/{document:[a-z,0-9,-]+}", HandlerFc: GetDocument(mongoClient), Methods: "GET"},
-		{Path: "/{database:[a-z]+}/{collection:[a-z]+}/{document:[a-z,0-9,-]+}", HandlerFc: PutDocument(mongoClient), Methods: "POST,PUT"},
-		{Path: "/{database:[a-z]+}/{collection:[a-z]+}/{document:[a-z,0-9,-]+}", HandlerFc: PatchDocument(mongoClient), Methods: "PATCH"},
-		{Path: "/{database}/{collection}/{document:[a-z,0-9,-]+}", HandlerFc: DeleteDocument(mongoClient), Methods: "DELETE"},
-		{Path: "/{database:[a-z]+}/{collection:[a-z]+}", HandlerFc: PutDocument(mongoClient), Methods: "POST,PUT"},
-		{Path: "/{database:[a-z]+}/{collection:[a-z]+}", HandlerFc: GetDocuments(mongoClient), Methods: "GET"},
-		{Path: "/{database:[a-z]+}", HandlerFc: getCollections(mongoClient), Methods: "GET"},
-		{Path: "/{database:[a-z]+}/{collection:[a-z]+}", HandlerFc: DeleteCollection(mongoClient), Methods: "DELETE"},
-		{Path: "/{database:[a-z]+}", HandlerFc: DeleteDatabase(mongoClient), Methods: "DELETE"},
-		{Path: "/", HandlerFc: GetDatabases(mongoClient), Methods: "GET"},
+		{Path: collectionPath + "/" + segments.Bulk, HandlerFc: BulkInsert(mongoClient), Methods: "POST", Cost: bulkCost, Group: RouteGroupWrite},
+		{Path: collectionPath + "/" + segments.Query, HandlerFc: QueryDocuments(mongoClient), Methods: "POST", Group: RouteGroupRead},
+		{Path: collectionPath + "/" + segments.Aggregate, HandlerFc: AggregateDocuments(mongoClient), Methods: "POST", Cost: aggregateCost, Group: RouteGroupRead},
+		{Path: collectionPath + "/" + segments.Count, HandlerFc: CountDocuments(mongoClient), Methods: "GET", Group: RouteGroupRead},
+		{Path: collectionPath + "/" + segments.Distinct + "/{field}", HandlerFc: DistinctValues(mongoClient), Methods: "GET", Group: RouteGroupRead},
+		{Path: collectionPath + "/" + segments.Trash, HandlerFc: GetTrash(mongoClient), Methods: "GET", Cost: fullScanCost, Group: RouteGroupRead},
+		{Path: documentPath + "/" + segments.Restore, HandlerFc: UndeleteDocument(mongoClient), Methods: "POST", Group: RouteGroupWrite},
+		{Path: documentPath + "/" + segments.History, HandlerFc: GetHistory(mongoClient), Methods: "GET", Group: RouteGroupRead},
+		{Path: revisionPath, HandlerFc: GetRevision(mongoClient), Methods: "GET", Group: RouteGroupRead},
+		{Path: revisionPath + "/" + segments.Restore, HandlerFc: RestoreRevision(mongoClient), Methods: "POST", Group: RouteGroupWrite},
+		{Path: documentPath, HandlerFc: getDocument(mongoClient, segments.Search), Methods: "GET", Group: RouteGroupRead},
+		{Path: documentPath, HandlerFc: PutDocument(mongoClient), Methods: "POST,PUT", Group: RouteGroupWrite},
+		{Path: documentPath, HandlerFc: PatchDocument(mongoClient), Methods: "PATCH", Group: RouteGroupWrite},
+		{Path: documentPath, HandlerFc: DeleteDocument(mongoClient), Methods: "DELETE", Group: RouteGroupWrite},
+		{Path: collectionPath, HandlerFc: PutDocument(mongoClient), Methods: "POST,PUT", Group: RouteGroupWrite},
+		{Path: collectionPath, HandlerFc: GetDocuments(mongoClient), Methods: "GET", Cost: fullScanCost, Group: RouteGroupRead},
+		{Path: "/" + databaseVar, HandlerFc: getCollections(mongoClient), Methods: "GET", Group: RouteGroupRead},
+		{Path: collectionPath, HandlerFc: DeleteCollection(mongoClient), Methods: "DELETE", Group: RouteGroupDrop},
+		{Path: "/" + databaseVar, HandlerFc: DeleteDatabase(mongoClient), Methods: "DELETE", Group: RouteGroupDrop},
+		{Path: "/", HandlerFc: GetDatabases(mongoClient), Methods: "GET", Group: RouteGroupRead},
+	}
+	for i := range routes {
+		routes[i].HandlerFc = withNamespace(mongoClient, routes[i].HandlerFc)
 	}
 	return routes
 }
+
 func getCollections(mongoClient *MongoClient) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		client := mongoClient.WithContext(r.Context())
 		vars := mux.Vars(r)
 		v := r.URL.Query()["nameOnly"]
 		database := vars["database"]
@@ -41,109 +98,97 @@ func getCollections(mongoClient *MongoClient) http.HandlerFunc {
 		if check(func() bool { return database == "" }, w) {
 			return
 		}
-		data, err := mongoClient.GetCollections(database, nameOnly)
-		if data == nil {
-			w.Write([]byte("[]"))
-			return
-		}
-		if checkError(err, w) {
-			return
-		}
-		jsonData, err := bson.MarshalExtJSON(bson.M{"body": data}, true, true)
-		if checkError(err, w) {
-			return
-		}
-		_, err = w.Write(jsonData)
-		if checkError(err, w) {
+		data, err := client.GetCollections(database, nameOnly)
+		if checkError(err, w, r) {
 			return
 		}
+		writeResponse(w, r, data)
 	}
 }
 
 func GetDocuments(mongoClient *MongoClient) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		client := mongoClient.WithContext(r.Context())
 		vars := mux.Vars(r)
 		database := vars["database"]
 		collection := vars["collection"]
 		if check(func() bool { return collection == "" || database == "" }, w) {
 			return
 		}
-		data, err := mongoClient.FindAll(database, collection)
-		if checkError(err, w) {
-			return
-		}
-		if data == nil {
-			w.Write([]byte("[]"))
+		opts, paginated, err := findOptions(r.URL.Query())
+		if checkError(err, w, r) {
 			return
 		}
-		jsonData, err := bson.MarshalExtJSON(bson.M{"body": data}, false, false)
-		if checkError(err, w) {
+		if paginated && checkError(setTotalCount(client, database, collection, bson.M{}, w, r), w, r) {
 			return
 		}
-		_, err = w.Write(jsonData)
-		if checkError(err, w) {
+		cursor, err := client.FindCursor(database, collection, bson.M{}, opts)
+		if checkError(err, w, r) {
 			return
 		}
+		writeCursor(client.Context(), cursor, w, r, client.afterFind(database, collection))
 	}
 }
 
 func DeleteDatabase(mongoClient *MongoClient) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		client := mongoClient.WithContext(r.Context())
 		vars := mux.Vars(r)
 		database := vars["database"]
 
 		if check(func() bool { return database == "" }, w) {
 			return
 		}
-		err := mongoClient.DropDatabase(database)
-		if checkError(err, w) {
+		err := client.DropDatabase(database)
+		if checkError(err, w, r) {
 			return
 		}
+		w.WriteHeader(http.StatusNoContent)
 	}
 }
 
 func DeleteCollection(mongoClient *MongoClient) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		client := mongoClient.WithContext(r.Context())
 		vars := mux.Vars(r)
 		database := vars["database"]
 		collection := vars["collection"]
 		if check(func() bool { return collection == "" || database == "" }, w) {
 			return
 		}
-		err := mongoClient.DropCollection(database, collection)
-		if checkError(err, w) {
+		err := client.DropCollection(database, collection)
+		if checkError(err, w, r) {
 			return
 		}
+		w.WriteHeader(http.StatusNoContent)
 	}
 }
 
 func GetDatabases(mongoClient *MongoClient) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		client := mongoClient.WithContext(r.Context())
 		v := r.URL.Query()["nameOnly"]
 		nameOnly := false
 		if v != nil && len(v) > 0 {
 			nameOnly, _ = strconv.ParseBool(v[0])
 		}
-		data, err := mongoClient.GetDatabases(&options.DatabaseOptions{}, nameOnly)
-		if checkError(err, w) {
-			return
-		}
-		if data == nil {
-			return
-		}
-		jsonData, err := bson.MarshalExtJSON(bson.M{"body": data}, true, true)
-		if checkError(err, w) {
-			return
-		}
-		_, err = w.Write(jsonData)
-		if checkError(err, w) {
+		data, err := client.GetDatabases(&options.DatabaseOptions{}, nameOnly)
+		if checkError(err, w, r) {
 			return
 		}
+		writeResponse(w, r, data)
 	}
 }
 
 func GetDocument(mongoClient *MongoClient) http.HandlerFunc {
+	return getDocument(mongoClient, defaultSearchSegment)
+}
+
+// getDocument answers the search segment with the documents matching the query parameters,
+// any other segment with the document of that ID.
+func getDocument(mongoClient *MongoClient, searchSegment string) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		client := mongoClient.WithContext(r.Context())
 		vars := mux.Vars(r)
 		database := vars["database"]
 		collection := vars["collection"]
@@ -156,167 +201,298 @@ func GetDocument(mongoClient *MongoClient) http.HandlerFunc {
 		data := make([]bson.M, 0)
 		var err error
 		switch document {
-		case "search":
-			for k, v := range r.URL.Query() {
-				if strings.HasPrefix(v[0], "_d") {
-					numValue, err := strconv.ParseInt(strings.TrimPrefix(v[0], "_d"), 10, 0)
-					if err != nil {
-						break
-					}
-					filter[k] = numValue
-				} else {
-					filter[k] = v[0]
-				}
+		case searchSegment:
+			filter = searchFilter(r.URL.Query())
+			if writeExplain(client, database, collection, filter, w, r) {
+				return
+			}
+			opts, paginated, err := findOptions(r.URL.Query())
+			if checkError(err, w, r) {
+				return
+			}
+			if paginated && checkError(setTotalCount(client, database, collection, filter, w, r), w, r) {
+				return
 			}
+			cursor, err := client.FindCursor(database, collection, filter, opts)
+			if checkError(err, w, r) {
+				return
+			}
+			writeCursor(client.Context(), cursor, w, r, client.afterFind(database, collection))
+			return
 		default:
-			filter = bson.M{"_id": document}
+			filter, err = documentFilter(document)
+			if checkError(err, w, r) {
+				return
+			}
 		}
-		data, err = mongoClient.FindMany(database, collection, filter)
-		if checkError(err, w) {
+		data, err = client.FindMany(database, collection, filter)
+		if checkError(err, w, r) {
 			return
 		}
-		if data == nil {
-			return
+		writeResponse(w, r, data)
+	}
+}
+
+// searchFilter matches the query parameters by equality, values prefixed with _d are
+// compared as numbers.
+func searchFilter(query url.Values) bson.M {
+	filter := bson.M{}
+	for k, v := range query {
+		if reservedParams[k] {
+			continue
+		}
+		if strings.HasPrefix(v[0], "_d") {
+			numValue, err := strconv.ParseInt(strings.TrimPrefix(v[0], "_d"), 10, 0)
+			if err != nil {
+				break
+			}
+			filter[k] = numValue
+		} else {
+			filter[k] = v[0]
 		}
-		jsonData, err := bson.MarshalExtJSON(bson.M{"body": data}, false, true)
-		if checkError(err, w) {
-			return
+	}
+	return filter
+}
+
+// findOptions reads limit, skip and batch size from the query, the result is paginated if
+// limit or skip are set.
+func findOptions(query url.Values) (*options.FindOptions, bool, error) {
+	opts := options.Find()
+	size, err := batchSize(query)
+	if err != nil {
+		return nil, false, err
+	}
+	if size > 0 {
+		opts.SetBatchSize(size)
+	}
+	paginated := false
+	if v := query.Get(limitParam); v != "" {
+		limit, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return nil, false, err
 		}
-		_, err = w.Write(jsonData)
-		if checkError(err, w) {
-			return
+		opts.SetLimit(limit)
+		paginated = true
+	}
+	if v := query.Get(skipParam); v != "" {
+		skip, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return nil, false, err
 		}
+		opts.SetSkip(skip)
+		paginated = true
 	}
+	return opts, paginated, nil
 }
 
-func PutDocument(mongoClient *MongoClient) http.HandlerFunc {
+func setTotalCount(client *MongoClient, database, collection string, filter bson.M, w http.ResponseWriter, r *http.Request) error {
+	total, err := client.Count(database, collection, filter)
+	if err != nil {
+		return err
+	}
+	w.Header().Set(totalCountHeader, strconv.FormatInt(total, 10))
+	setTotal(r.Context(), total)
+	return nil
+}
+
+func CountDocuments(mongoClient *MongoClient) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		client := mongoClient.WithContext(r.Context())
 		vars := mux.Vars(r)
 		database := vars["database"]
 		collection := vars["collection"]
-		document := vars["document"]
 		if check(func() bool { return collection == "" || database == "" }, w) {
 			return
 		}
+		var count int64
+		var err error
+		filter := searchFilter(r.URL.Query())
+		estimated, _ := strconv.ParseBool(r.URL.Query().Get(estimatedParam))
+		if estimated && len(filter) == 0 {
+			count, err = client.EstimatedCount(database, collection)
+		} else {
+			if estimated {
+				AddWarning(r.Context(), "estimated count is not available with a filter, counted exactly")
+			}
+			count, err = client.Count(database, collection, filter)
+		}
+		if checkError(err, w, r) {
+			return
+		}
+		writeResponse(w, r, bson.M{"count": count})
+	}
+}
 
-		body, err := ioutil.ReadAll(r.Body)
-		if checkError(err, w) {
+func DistinctValues(mongoClient *MongoClient) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		client := mongoClient.WithContext(r.Context())
+		vars := mux.Vars(r)
+		database := vars["database"]
+		collection := vars["collection"]
+		field := vars["field"]
+		if check(func() bool { return collection == "" || database == "" || field == "" }, w) {
 			return
 		}
-		doc := bson.M{}
-		err = bson.UnmarshalExtJSON(body, true, &doc)
-		if checkError(err, w) {
+		data, err := client.Distinct(database, collection, field, searchFilter(r.URL.Query()))
+		if checkError(err, w, r) {
 			return
 		}
-		var data bson.M
-		if document != "" {
-			filter := bson.M{"_id": document}
-			opts := &options.FindOneAndReplaceOptions{
-				Upsert: proto.Bool(true),
-			}
-			data, err = mongoClient.ReplaceOne(database, collection, filter, doc, opts)
-		} else {
-			doc["_id"] = uuid.New().String()
-			data, err = mongoClient.InsertOne(database, collection, doc)
+		writeResponse(w, r, data)
+	}
+}
+
+func QueryDocuments(mongoClient *MongoClient) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		client := mongoClient.WithContext(r.Context())
+		vars := mux.Vars(r)
+		database := vars["database"]
+		collection := vars["collection"]
+		if check(func() bool { return collection == "" || database == "" }, w) {
+			return
 		}
-		if checkError(err, w) {
+		filter, err := readDocument(r)
+		if checkError(err, w, r) {
 			return
 		}
-		if data == nil {
+		if writeExplain(client, database, collection, filter, w, r) {
 			return
 		}
-		jsonData, err := bson.MarshalExtJSON(bson.M{"body": data}, true, true)
-		if checkError(err, w) {
+		opts, _, err := findOptions(r.URL.Query())
+		if checkError(err, w, r) {
 			return
 		}
-		_, err = w.Write(jsonData)
-		if checkError(err, w) {
+		cursor, err := client.FindCursor(database, collection, filter, opts)
+		if checkError(err, w, r) {
 			return
 		}
+		writeCursor(client.Context(), cursor, w, r, client.afterFind(database, collection))
 	}
 }
 
-func PatchDocument(mongoClient *MongoClient) http.HandlerFunc {
+func AggregateDocuments(mongoClient *MongoClient) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		client := mongoClient.WithContext(r.Context())
 		vars := mux.Vars(r)
 		database := vars["database"]
 		collection := vars["collection"]
-		document := vars["document"]
-		if check(func() bool { return collection == "" || database == "" || document == "" }, w) {
+		if check(func() bool { return collection == "" || database == "" }, w) {
 			return
 		}
-
-		body, err := ioutil.ReadAll(r.Body)
-		if checkError(err, w) {
+		pipeline, err := readPipeline(r)
+		if checkError(err, w, r) {
 			return
 		}
-		doc := bson.M{}
-		err = bson.UnmarshalExtJSON(body, true, &doc)
-		if checkError(err, w) {
+		if writeExplain(client, database, collection, pipeline, w, r) {
 			return
 		}
-		id, err := primitive.ObjectIDFromHex(document)
-		if checkError(err, w) {
+		size, err := batchSize(r.URL.Query())
+		if checkError(err, w, r) {
 			return
 		}
-		filter := bson.M{"_id": id}
-		data, err := mongoClient.UpdateOne(database, collection, filter, doc)
-		if checkError(err, w) {
+		opts := options.Aggregate()
+		if size > 0 {
+			opts.SetBatchSize(size)
+		}
+		cursor, err := client.Query(database, collection, pipeline, opts)
+		if checkError(err, w, r) {
 			return
 		}
-		if data == nil {
+		// aggregation results are not documents of the collection, AfterFind does not apply
+		writeCursor(client.Context(), cursor, w, r, nil)
+	}
+}
+
+func PutDocument(mongoClient *MongoClient) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		client := mongoClient.WithContext(r.Context())
+		vars := mux.Vars(r)
+		database := vars["database"]
+		collection := vars["collection"]
+		document := vars["document"]
+		if check(func() bool { return collection == "" || database == "" }, w) {
 			return
 		}
-		jsonData, err := bson.MarshalExtJSON(bson.M{"body": data}, true, true)
-		if checkError(err, w) {
+
+		doc, err := readDocument(r)
+		if checkError(err, w, r) {
 			return
 		}
-		_, err = w.Write(jsonData)
-		if checkError(err, w) {
+		var data bson.M
+		if document != "" {
+			var id interface{}
+			id, err = resolveDocumentID(client, database, collection, document)
+			if checkError(err, w, r) {
+				return
+			}
+			filter := bson.M{documentIDField: id}
+			opts := &options.FindOneAndReplaceOptions{
+				Upsert: proto.Bool(true),
+			}
+			data, err = client.ReplaceOne(database, collection, filter, doc, opts)
+		} else {
+			doc[documentIDField] = uuid.New().String()
+			data, err = client.InsertOne(database, collection, doc)
+		}
+		if checkError(err, w, r) {
 			return
 		}
+		writeResponse(w, r, data)
 	}
 }
 
-func postDocument(mongoClient *MongoClient) http.HandlerFunc {
+func PatchDocument(mongoClient *MongoClient) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		client := mongoClient.WithContext(r.Context())
 		vars := mux.Vars(r)
 		database := vars["database"]
 		collection := vars["collection"]
-		if check(func() bool { return collection == "" || database == "" }, w) {
+		document := vars["document"]
+		if check(func() bool { return collection == "" || database == "" || document == "" }, w) {
 			return
 		}
 
-		body, err := ioutil.ReadAll(r.Body)
-		if checkError(err, w) {
+		doc, err := readDocument(r)
+		if checkError(err, w, r) {
 			return
 		}
-		doc := bson.M{}
-		err = bson.UnmarshalExtJSON(body, true, &doc)
-		if checkError(err, w) {
+		filter, err := documentFilter(document)
+		if checkError(err, w, r) {
 			return
 		}
-
-		data, err := mongoClient.InsertOne(database, collection, doc)
-		if checkError(err, w) {
+		data, err := client.UpdateOne(database, collection, filter, doc)
+		if checkError(err, w, r) {
 			return
 		}
-		if data == nil {
+		writeResponse(w, r, data)
+	}
+}
+
+func postDocument(mongoClient *MongoClient) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		client := mongoClient.WithContext(r.Context())
+		vars := mux.Vars(r)
+		database := vars["database"]
+		collection := vars["collection"]
+		if check(func() bool { return collection == "" || database == "" }, w) {
 			return
 		}
-		jsonData, err := bson.MarshalExtJSON(bson.M{"body": data}, true, true)
-		if checkError(err, w) {
+
+		doc, err := readDocument(r)
+		if checkError(err, w, r) {
 			return
 		}
-		_, err = w.Write(jsonData)
-		if checkError(err, w) {
+
+		data, err := client.InsertOne(database, collection, doc)
+		if checkError(err, w, r) {
 			return
 		}
+		writeResponse(w, r, data)
 	}
 }
 
 func DeleteDocument(mongoClient *MongoClient) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		client := mongoClient.WithContext(r.Context())
 		vars := mux.Vars(r)
 		database := vars["database"]
 		collection := vars["collection"]
@@ -324,14 +500,42 @@ func DeleteDocument(mongoClient *MongoClient) http.HandlerFunc {
 		if check(func() bool { return collection == "" || database == "" || id == "" }, w) {
 			return
 		}
-		filter := bson.M{"_id": id}
-		err := mongoClient.DeleteOne(database, collection, filter)
-		if checkError(err, w) {
+		filter, err := documentFilter(id)
+		if checkError(err, w, r) {
+			return
+		}
+		err = client.DeleteOne(database, collection, filter)
+		if checkError(err, w, r) {
 			return
 		}
+		w.WriteHeader(http.StatusNoContent)
 	}
 }
 
+// resolveDocumentID returns the _id of the document matching the path segment, or the ID
+// parsed from the segment if there is none yet. Soft deleted documents are matched as well,
+// so that their ID is not taken by a second document of another type.
+func resolveDocumentID(client *MongoClient, database, collection, document string) (interface{}, error) {
+	filter, err := documentFilter(document)
+	if err != nil {
+		return nil, err
+	}
+	col, err := client.GetCollection(database, collection, client.config.databaseOptions, client.config.collectionOptions)
+	if err != nil {
+		return nil, err
+	}
+	var doc bson.M
+	opts := options.FindOne().SetProjection(bson.M{documentIDField: 1})
+	err = col.FindOne(client.Context(), filter, opts).Decode(&doc)
+	if err == nil {
+		return doc[documentIDField], nil
+	}
+	if !errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, err
+	}
+	return documentID(document)
+}
+
 func check(condition func() bool, w http.ResponseWriter) bool {
 	if condition() {
 		http.Error(w, "BadRequest", http.StatusBadRequest)
@@ -340,20 +544,110 @@ func check(condition func() bool, w http.ResponseWriter) bool {
 	return false
 }
 
-func checkError(err error, w http.ResponseWriter) bool {
+func checkError(err error, w http.ResponseWriter, r *http.Request) bool {
+	if isTooLarge(err) {
+		logError(r, err)
+		http.Error(w, "RequestEntityTooLarge", http.StatusRequestEntityTooLarge)
+		return true
+	}
+	var hookErr *HookError
+	if errors.As(err, &hookErr) {
+		logError(r, err)
+		http.Error(w, hookErr.Message, hookErr.status())
+		return true
+	}
+	if errors.Is(err, errReservedNamespace) || errors.Is(err, errReadOnlyStage) {
+		logError(r, err)
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return true
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		logError(r, err)
+		http.Error(w, "GatewayTimeout", http.StatusGatewayTimeout)
+		return true
+	}
+	if isDuplicateKey(err) {
+		logError(r, err)
+		http.Error(w, "Conflict", http.StatusConflict)
+		return true
+	}
+	if errors.Is(err, mongo.ErrNoDocuments) || errors.Is(err, errHistoryDisabled) || errors.Is(err, errSoftDeleteDisabled) {
+		http.Error(w, "NotFound", http.StatusNotFound)
+		return true
+	}
 	return check(func() bool {
 		if err != nil {
-			log.Println(err)
+			logError(r, err)
 		}
 		return err != nil
 	}, w)
 }
 
-func checkDataAndError(data interface{}, err error, w http.ResponseWriter) bool {
+func checkDataAndError(data interface{}, err error, w http.ResponseWriter, r *http.Request) bool {
 	return check(func() bool {
 		if err != nil {
-			log.Println(err)
+			logError(r, err)
 		}
 		return err != nil || data == nil
 	}, w)
 }
+
+// isTooLarge matches the error of http.MaxBytesReader by its message, http.MaxBytesError
+// needs Go 1.19.
+func isTooLarge(err error) bool {
+	return err != nil && (errors.Is(err, errDocumentTooLarge) || strings.Contains(err.Error(), "http: request body too large"))
+}
+
+func readBody(r *http.Request) ([]byte, error) {
+	if r.ContentLength > maxDocumentSize {
+		return nil, errDocumentTooLarge
+	}
+	body, err := ioutil.ReadAll(io.LimitReader(r.Body, maxDocumentSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(body) > maxDocumentSize {
+		return nil, errDocumentTooLarge
+	}
+	return body, nil
+}
+
+func readDocument(r *http.Request) (bson.M, error) {
+	body, err := readBody(r)
+	if err != nil {
+		return nil, err
+	}
+	doc := bson.M{}
+	err = bson.UnmarshalExtJSON(body, true, &doc)
+	return doc, err
+}
+
+// readPipeline accepts a bare pipeline array or a document with a pipeline element.
+func readPipeline(r *http.Request) (bson.A, error) {
+	body, err := readBody(r)
+	if err != nil {
+		return nil, err
+	}
+	body = bytes.TrimSpace(body)
+	if len(body) > 0 && body[0] == '[' {
+		body = append(append([]byte(`{"pipeline":`), body...), '}')
+	}
+	var doc struct {
+		Pipeline bson.A `bson:"pipeline"`
+	}
+	err = bson.UnmarshalExtJSON(body, true, &doc)
+	if err != nil {
+		return nil, err
+	}
+	if doc.Pipeline == nil {
+		return nil, errNoPipeline
+	}
+	if isReadOnly(r.Context()) {
+		for _, stage := range doc.Pipeline {
+			if s, ok := asMap(stage); ok && (s["$out"] != nil || s["$merge"] != nil) {
+				return nil, errReadOnlyStage
+			}
+		}
+	}
+	return doc.Pipeline, nil
+}
@@ -0,0 +1,107 @@
+package mongo
+
+import (
+	"context"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
+	"net/http"
+	"time"
+)
+
+const (
+	statusUp   = "up"
+	statusDown = "down"
+)
+
+var startTime = time.Now()
+
+type HealthCheck struct {
+	Name      string  `bson:"name"`
+	Status    string  `bson:"status"`
+	LatencyMs float64 `bson:"latencyMs"`
+	Error     string  `bson:"error,omitempty"`
+}
+
+func HealthRoutes(mongoClient *MongoClient) []Route {
+	return []Route{
+		{Path: "/healthz", HandlerFc: Healthz(), Methods: "GET", Group: RouteGroupHealth},
+		{Path: "/livez", HandlerFc: Livez(), Methods: "GET", Group: RouteGroupHealth},
+		{Path: "/readyz", HandlerFc: Readyz(mongoClient), Methods: "GET", Group: RouteGroupHealth},
+	}
+}
+
+// Ping checks that a server matching the read preference is reachable within the configured timeout.
+func (b MongoClient) Ping(rp *readpref.ReadPref) error {
+	ctx, cancel := context.WithTimeout(b.Context(), b.timeout())
+	defer cancel()
+	return b.client.Ping(ctx, rp)
+}
+
+func (b MongoClient) ServerVersion() (string, error) {
+	ctx, cancel := context.WithTimeout(b.Context(), b.timeout())
+	defer cancel()
+	var info struct {
+		Version string `bson:"version"`
+	}
+	err := b.client.Database("admin").RunCommand(ctx, bson.M{"buildInfo": 1}).Decode(&info)
+	return info.Version, err
+}
+
+func (b MongoClient) timeout() time.Duration {
+	if b.config.Timeout > 0 {
+		return b.config.Timeout
+	}
+	return timeout
+}
+
+func Healthz() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		writeResponse(w, r, bson.M{"status": statusUp})
+	}
+}
+
+func Livez() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		writeResponse(w, r, bson.M{"status": statusUp, "uptime": time.Since(startTime).String()})
+	}
+}
+
+func Readyz(mongoClient *MongoClient) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		client := mongoClient.WithContext(r.Context())
+		checks := []HealthCheck{
+			runCheck("mongo", func() error { return client.Ping(readpref.Nearest()) }),
+			runCheck("primary", func() error { return client.Ping(readpref.Primary()) }),
+		}
+		status := statusUp
+		for _, c := range checks {
+			if c.Status != statusUp {
+				status = statusDown
+			}
+		}
+		result := bson.M{"status": status, "checks": checks}
+		if status != statusUp {
+			writeResponseStatus(w, r, http.StatusServiceUnavailable, result)
+			return
+		}
+		if version, err := client.ServerVersion(); err == nil {
+			result["serverVersion"] = version
+		}
+		writeResponse(w, r, result)
+	}
+}
+
+func runCheck(name string, fn func() error) HealthCheck {
+	start := time.Now()
+	err := fn()
+	check := HealthCheck{
+		Name:      name,
+		Status:    statusUp,
+		LatencyMs: float64(time.Since(start).Microseconds()) / 1000,
+	}
+	if err != nil {
+		check.Status = statusDown
+		check.Error = err.Error()
+	}
+	return check
+}
@@ -1,10 +0,0 @@
-package mongo
-
-import (
-	"net/http"
-)
-
-func enableCors(w *http.ResponseWriter) {
-	(*w).Header().Set("Access-Control-Allow-Origin", "*")
-	(*w).Header().Set("Access-Control-Allow-Methods", "*")
-}
@@ -0,0 +1,244 @@
+package mongo
+
+import (
+	"errors"
+	"github.com/gorilla/mux"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultHistorySuffix = "_history"
+
+	errCodeDuplicateKey = 11000
+	// revisionAttempts bounds the retries of concurrent writers taking the same version
+	revisionAttempts = 5
+)
+
+var errHistoryDisabled = errors.New("collection is not versioned")
+
+// HistoryConfig enables versioning of the collections in Namespaces, given as
+// "database.collection" or "database.*". The prior revision of every replaced, updated or
+// deleted document is stored in the collection with Suffix appended to its name.
+type HistoryConfig struct {
+	Namespaces []string
+	Suffix     string
+}
+
+type Revision struct {
+	DocumentID interface{} `bson:"documentId"`
+	Version    int64       `bson:"version"`
+	Timestamp  time.Time   `bson:"ts"`
+	Principal  string      `bson:"principal,omitempty"`
+	Operation  string      `bson:"op"`
+	Document   bson.M      `bson:"document"`
+}
+
+func (c HistoryConfig) suffix() string {
+	if c.Suffix == "" {
+		return defaultHistorySuffix
+	}
+	return c.Suffix
+}
+
+func (b MongoClient) versioned(database, collection string) bool {
+	if b.config == nil || b.config.History == nil || strings.HasSuffix(collection, b.config.History.suffix()) {
+		return false
+	}
+	return matchNamespace(b.config.History.Namespaces, database, collection)
+}
+
+func (b MongoClient) historyCollection(database, collection string) (*mongo.Collection, error) {
+	if !b.versioned(database, collection) {
+		return nil, errHistoryDisabled
+	}
+	return b.GetCollection(database, collection+b.config.History.suffix(), b.config.databaseOptions, b.config.collectionOptions)
+}
+
+// saveRevision stores before as the next revision of its document if the collection is
+// versioned. Versions start at 1 for the first stored revision. It runs after the write, which
+// returned before, so a failure is logged and does not undo the write.
+func (b MongoClient) saveRevision(op, database, collection string, before bson.M) {
+	if before == nil || !b.versioned(database, collection) {
+		return
+	}
+	if err := b.insertRevision(op, database, collection, before); err != nil {
+		b.logger().Error("saving revision failed", "database", database, "collection", collection,
+			"id", before[documentIDField], "error", err)
+	}
+}
+
+// insertRevision takes the next version of the document, the unique index on documentId and
+// version makes concurrent writers retry with the following one.
+func (b MongoClient) insertRevision(op, database, collection string, before bson.M) error {
+	col, err := b.historyCollection(database, collection)
+	if err != nil {
+		return err
+	}
+	if err := b.ensureHistoryIndex(col); err != nil {
+		return err
+	}
+	id := before[documentIDField]
+	for attempt := 1; ; attempt++ {
+		version := int64(1)
+		var latest Revision
+		err = col.FindOne(b.Context(), bson.M{"documentId": id}, options.FindOne().SetSort(bson.M{"version": -1})).Decode(&latest)
+		if err == nil {
+			version = latest.Version + 1
+		} else if !errors.Is(err, mongo.ErrNoDocuments) {
+			return err
+		}
+		_, err = col.InsertOne(b.Context(), Revision{
+			DocumentID: id,
+			Version:    version,
+			Timestamp:  time.Now(),
+			Principal:  PrincipalFromContext(b.Context()),
+			Operation:  op,
+			Document:   before,
+		})
+		if !isDuplicateKey(err) || attempt == revisionAttempts {
+			return err
+		}
+	}
+}
+
+// ensureHistoryIndex creates the unique index of the versions once per history collection.
+func (b MongoClient) ensureHistoryIndex(col *mongo.Collection) error {
+	namespace := col.Database().Name() + "." + col.Name()
+	if b.historyIndexes == nil {
+		return errors.New("Mongo client must be created by NewMongoClient")
+	}
+	if _, ok := b.historyIndexes.Load(namespace); ok {
+		return nil
+	}
+	_, err := col.Indexes().CreateOne(b.Context(), mongo.IndexModel{
+		Keys:    bson.D{{Key: "documentId", Value: 1}, {Key: "version", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	if err == nil {
+		b.historyIndexes.Store(namespace, true)
+	}
+	return err
+}
+
+func isDuplicateKey(err error) bool {
+	var writeErr mongo.WriteException
+	if errors.As(err, &writeErr) {
+		for _, e := range writeErr.WriteErrors {
+			if e.Code == errCodeDuplicateKey {
+				return true
+			}
+		}
+	}
+	var cmdErr mongo.CommandError
+	return errors.As(err, &cmdErr) && cmdErr.Code == errCodeDuplicateKey
+}
+
+// History returns the stored revisions of a document, the latest first. id may be a query
+// operator, e.g. {"$in": [...]}.
+func (b MongoClient) History(database, collection string, id interface{}) ([]Revision, error) {
+	col, err := b.historyCollection(database, collection)
+	if err != nil {
+		return nil, err
+	}
+	cursor, err := col.Find(b.Context(), bson.M{"documentId": id}, options.Find().SetSort(bson.M{"version": -1}))
+	if err != nil {
+		return nil, err
+	}
+	result := make([]Revision, 0)
+	err = cursor.All(b.Context(), &result)
+	return result, err
+}
+
+func (b MongoClient) Revision(database, collection string, id interface{}, version int64) (*Revision, error) {
+	col, err := b.historyCollection(database, collection)
+	if err != nil {
+		return nil, err
+	}
+	res := col.FindOne(b.Context(), bson.M{"documentId": id, "version": version})
+	if res.Err() != nil {
+		return nil, res.Err()
+	}
+	var revision Revision
+	err = res.Decode(&revision)
+	return &revision, err
+}
+
+// Restore replaces the document by the revision of version, or recreates it if it has been
+// deleted. The replaced document is stored as new revision, so a restore can be undone.
+func (b MongoClient) Restore(database, collection string, id interface{}, version int64) (bson.M, error) {
+	revision, err := b.Revision(database, collection, id, version)
+	if err != nil {
+		return nil, err
+	}
+	opts := options.FindOneAndReplace().SetUpsert(true)
+	return b.ReplaceOne(database, collection, bson.M{documentIDField: revision.DocumentID}, revision.Document, opts)
+}
+
+func historyRequest(w http.ResponseWriter, r *http.Request) (database, collection string, id interface{}, version int64, ok bool) {
+	vars := mux.Vars(r)
+	database = vars["database"]
+	collection = vars["collection"]
+	filter, err := documentFilter(vars["document"])
+	if checkError(err, w, r) {
+		return
+	}
+	id = filter[documentIDField]
+	if v, found := vars["version"]; found {
+		version, err = strconv.ParseInt(v, 10, 64)
+		if checkError(err, w, r) {
+			return
+		}
+	}
+	return database, collection, id, version, true
+}
+
+func GetHistory(mongoClient *MongoClient) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		client := mongoClient.WithContext(r.Context())
+		database, collection, id, _, ok := historyRequest(w, r)
+		if !ok {
+			return
+		}
+		data, err := client.History(database, collection, id)
+		if checkError(err, w, r) {
+			return
+		}
+		writeResponse(w, r, data)
+	}
+}
+
+func GetRevision(mongoClient *MongoClient) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		client := mongoClient.WithContext(r.Context())
+		database, collection, id, version, ok := historyRequest(w, r)
+		if !ok {
+			return
+		}
+		data, err := client.Revision(database, collection, id, version)
+		if checkError(err, w, r) {
+			return
+		}
+		writeResponse(w, r, data)
+	}
+}
+
+func RestoreRevision(mongoClient *MongoClient) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		client := mongoClient.WithContext(r.Context())
+		database, collection, id, version, ok := historyRequest(w, r)
+		if !ok {
+			return
+		}
+		data, err := client.Restore(database, collection, id, version)
+		if checkError(err, w, r) {
+			return
+		}
+		writeResponse(w, r, data)
+	}
+}
@@ -0,0 +1,148 @@
+package mongo
+
+import (
+	"context"
+	"go.mongodb.org/mongo-driver/bson"
+	"net/http"
+	"sync"
+)
+
+// Hooks run around the operations of the MongoClient on a collection. Before hooks may
+// change the documents or veto the operation by returning an error, a *HookError is answered
+// with its status by the REST handlers. AfterFind may change or enrich every document read.
+// Unset hooks are skipped.
+type Hooks struct {
+	BeforeInsert func(ctx context.Context, doc bson.M) error
+	AfterInsert  func(ctx context.Context, doc bson.M)
+	BeforeUpdate func(ctx context.Context, filter bson.M, update bson.M) error
+	BeforeDelete func(ctx context.Context, filter bson.M) error
+	AfterFind    func(ctx context.Context, doc bson.M) error
+}
+
+// HookError vetoes an operation, Status is the HTTP status of the response and defaults to
+// 422 Unprocessable Entity.
+type HookError struct {
+	Status  int
+	Message string
+}
+
+func (e *HookError) Error() string {
+	return e.Message
+}
+
+func (e *HookError) status() int {
+	if e.Status == 0 {
+		return http.StatusUnprocessableEntity
+	}
+	return e.Status
+}
+
+// Veto returns a HookError answered with 422 Unprocessable Entity.
+func Veto(message string) error {
+	return &HookError{Status: http.StatusUnprocessableEntity, Message: message}
+}
+
+type hookRegistry struct {
+	mu    sync.RWMutex
+	hooks map[string][]Hooks
+}
+
+func newHookRegistry() *hookRegistry {
+	return &hookRegistry{hooks: map[string][]Hooks{}}
+}
+
+// RegisterHooks adds hooks for the collection, "*" registers them for all collections of the
+// database. Hooks run in the order of registration, collection hooks after database hooks.
+func (b MongoClient) RegisterHooks(database, collection string, hooks Hooks) {
+	b.hooks.mu.Lock()
+	defer b.hooks.mu.Unlock()
+	namespace := database + "." + collection
+	b.hooks.hooks[namespace] = append(b.hooks.hooks[namespace], hooks)
+}
+
+func (b MongoClient) hooksOf(database, collection string) []Hooks {
+	if b.hooks == nil {
+		return nil
+	}
+	b.hooks.mu.RLock()
+	defer b.hooks.mu.RUnlock()
+	result := append([]Hooks{}, b.hooks.hooks[database+".*"]...)
+	return append(result, b.hooks.hooks[database+"."+collection]...)
+}
+
+func (b MongoClient) beforeInsert(database, collection string, doc bson.M) error {
+	for _, hooks := range b.hooksOf(database, collection) {
+		if hooks.BeforeInsert != nil {
+			if err := hooks.BeforeInsert(b.Context(), doc); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (b MongoClient) afterInsert(database, collection string, doc bson.M) {
+	for _, hooks := range b.hooksOf(database, collection) {
+		if hooks.AfterInsert != nil {
+			hooks.AfterInsert(b.Context(), doc)
+		}
+	}
+}
+
+func (b MongoClient) beforeUpdate(database, collection string, filter, update bson.M) error {
+	for _, hooks := range b.hooksOf(database, collection) {
+		if hooks.BeforeUpdate != nil {
+			if err := hooks.BeforeUpdate(b.Context(), filter, update); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (b MongoClient) beforeDelete(database, collection string, filter bson.M) error {
+	for _, hooks := range b.hooksOf(database, collection) {
+		if hooks.BeforeDelete != nil {
+			if err := hooks.BeforeDelete(b.Context(), filter); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// afterFind returns the AfterFind hooks of the collection as one function, or nil if
+// there are none so documents can be passed on without decoding.
+func (b MongoClient) afterFind(database, collection string) func(doc bson.M) error {
+	var fns []func(ctx context.Context, doc bson.M) error
+	for _, hooks := range b.hooksOf(database, collection) {
+		if hooks.AfterFind != nil {
+			fns = append(fns, hooks.AfterFind)
+		}
+	}
+	if len(fns) == 0 {
+		return nil
+	}
+	ctx := b.Context()
+	return func(doc bson.M) error {
+		for _, fn := range fns {
+			if err := fn(ctx, doc); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+func (b MongoClient) afterFindAll(database, collection string, docs []bson.M) error {
+	hook := b.afterFind(database, collection)
+	if hook == nil {
+		return nil
+	}
+	for _, doc := range docs {
+		if err := hook(doc); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -4,9 +4,12 @@ import (
 	"errors"
 	"github.com/aws/aws-sdk-go/aws"
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
+// InsertOrReplace replaces the document matching filter and returns the replaced document,
+// or inserts update and returns nil.
 func (b MongoClient) InsertOrReplace(database, collection string, filter bson.M, update interface{}) (bson.M, error) {
 	opts := &options.FindOneAndReplaceOptions{
 		Upsert: aws.Bool(true),
@@ -15,13 +18,62 @@ func (b MongoClient) InsertOrReplace(database, collection string, filter bson.M,
 	if err != nil {
 		return nil, err
 	}
-	result := col.FindOneAndReplace(Ctx(), filter, update, opts)
-	if result.Err() != nil {
-		return nil, result.Err()
+	doc, _ := update.(bson.M)
+	before, err := b.replace(col, filter, update, doc, opts)
+	if err != nil {
+		return nil, err
+	}
+	if before != nil {
+		b.auditRecord(AuditReplace, database, collection, before[documentIDField], before, b.auditAfter(col, before))
+		return before, nil
+	}
+	b.auditRecord(AuditInsert, database, collection, doc[documentIDField], nil, doc)
+	if doc != nil {
+		b.afterInsert(database, collection, doc)
+	}
+	return nil, nil
+}
+
+// replace replaces the document matching filter by replacement, doc is the replacement if
+// it is a document. It returns the replaced document, read atomically with the write, or
+// nil if nothing was replaced and the replacement was inserted by an upsert. The insert hooks
+// run instead of the update hooks if an upsert will insert, the caller runs AfterInsert.
+func (b MongoClient) replace(col *mongo.Collection, filter bson.M, replacement interface{}, doc bson.M, opts ...*options.FindOneAndReplaceOptions) (bson.M, error) {
+	database, collection := col.Database().Name(), col.Name()
+	// a deleted document is not replaced, an upsert then fails with a duplicate key
+	filter = b.visible(database, collection, filter)
+	if doc != nil {
+		current := b.currentDocument(col, filter)
+		if current == nil && upserts(opts) {
+			if err := b.beforeInsert(database, collection, doc); err != nil {
+				return nil, err
+			}
+		} else if err := b.beforeUpdate(database, collection, filter, doc); err != nil {
+			return nil, err
+		}
+		b.stamp(database, collection, doc, current == nil, current)
+	}
+	opts = append(opts, options.FindOneAndReplace().SetReturnDocument(options.Before))
+	var before bson.M
+	err := col.FindOneAndReplace(b.Context(), filter, replacement, opts...).Decode(&before)
+	if errors.Is(err, mongo.ErrNoDocuments) && upserts(opts) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	b.saveRevision(AuditReplace, database, collection, before)
+	return before, nil
+}
+
+func upserts(opts []*options.FindOneAndReplaceOptions) bool {
+	upsert := false
+	for _, o := range opts {
+		if o != nil && o.Upsert != nil {
+			upsert = *o.Upsert
+		}
 	}
-	var resp bson.M
-	err = result.Decode(&resp)
-	return resp, err
+	return upsert
 }
 
 func (b MongoClient) InsertOne(database string, collection string, doc bson.M) (bson.M, error) {
@@ -29,7 +81,11 @@ func (b MongoClient) InsertOne(database string, collection string, doc bson.M) (
 	if err != nil {
 		return nil, err
 	}
-	res, err := col.InsertOne(Ctx(), doc)
+	if err := b.beforeInsert(database, collection, doc); err != nil {
+		return nil, err
+	}
+	b.stamp(database, collection, doc, true, nil)
+	res, err := col.InsertOne(b.Context(), doc)
 	if err != nil {
 		return nil, err
 	}
@@ -37,29 +93,85 @@ func (b MongoClient) InsertOne(database string, collection string, doc bson.M) (
 		return nil, errors.New("result must not be nil")
 	}
 	id := res.InsertedID
-	doc, err = FindOne(col, bson.M{documentIDField: id})
+	doc, err = findOne(b.Context(), col, bson.M{documentIDField: id})
+	b.auditRecord(AuditInsert, database, collection, id, nil, doc)
+	if err == nil {
+		b.afterInsert(database, collection, doc)
+	}
 	return doc, err
 }
 
+func (b MongoClient) InsertMany(database string, collection string, docs []interface{}) ([]interface{}, error) {
+	col, err := b.GetCollection(database, collection, b.config.databaseOptions, b.config.collectionOptions)
+	if err != nil {
+		return nil, err
+	}
+	for _, doc := range docs {
+		if doc, ok := doc.(bson.M); ok {
+			if err := b.beforeInsert(database, collection, doc); err != nil {
+				return nil, err
+			}
+			b.stamp(database, collection, doc, true, nil)
+		}
+	}
+	res, err := col.InsertMany(b.Context(), docs, &options.InsertManyOptions{})
+	if err != nil {
+		return nil, err
+	}
+	for i, id := range res.InsertedIDs {
+		after, _ := docs[i].(bson.M)
+		b.auditRecord(AuditInsert, database, collection, id, nil, after)
+		if after != nil {
+			b.afterInsert(database, collection, after)
+		}
+	}
+	return res.InsertedIDs, nil
+}
+
 func (b MongoClient) ReplaceOne(database string, collection string, filter bson.M, replacement bson.M, opts ...*options.FindOneAndReplaceOptions) (bson.M, error) {
 	col, err := b.GetCollection(database, collection, b.config.databaseOptions, b.config.collectionOptions)
 	if err != nil {
 		return nil, err
 	}
-	err = FindOneAndReplace(col, filter, replacement, opts...)
-	replacement, err = FindOne(col, filter)
+	before, err := b.replace(col, filter, replacement, replacement, opts...)
 	if err != nil {
 		return nil, err
 	}
+	replacement, err = findOne(b.Context(), col, filter)
+	if err != nil {
+		return nil, err
+	}
+	if before != nil {
+		b.auditRecord(AuditReplace, database, collection, replacement[documentIDField], before, replacement)
+	} else {
+		b.auditRecord(AuditInsert, database, collection, replacement[documentIDField], nil, replacement)
+		b.afterInsert(database, collection, replacement)
+	}
 	return replacement, err
 }
 
 func (b MongoClient) UpdateOne(database string, collection string, filter bson.M, update bson.M) (bson.M, error) {
-	upd := bson.M{"$set": update}
+	set := bson.M{}
+	for k, v := range update {
+		set[k] = v
+	}
+	if err := b.beforeUpdate(database, collection, filter, set); err != nil {
+		return nil, err
+	}
+	b.stamp(database, collection, set, false, nil)
+	upd := bson.M{"$set": set}
 	col, err := b.GetCollection(database, collection, b.config.databaseOptions, b.config.collectionOptions)
 	if err != nil {
 		return nil, err
 	}
-	err = FindOneAndUpdate(col, filter, upd)
-	return update, err
+	filter = b.visible(database, collection, filter)
+	var before bson.M
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.Before)
+	if err := col.FindOneAndUpdate(b.Context(), filter, upd, opts).Decode(&before); err != nil {
+		return set, err
+	}
+	b.saveRevision(AuditUpdate, database, collection, before)
+	b.auditRecord(AuditUpdate, database, collection, before[documentIDField], before, b.auditAfter(col, before))
+	// the fields as changed by the hooks and stamps, not as sent by the caller
+	return set, nil
 }
@@ -0,0 +1,143 @@
+package mongo
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"log/slog"
+	"os"
+	"sync"
+)
+
+// Logger is a leveled, structured logger. keysAndValues are alternating field names and values.
+// *slog.Logger implements Logger, zap can be plugged in with NewZapLogger.
+type Logger interface {
+	Debug(msg string, keysAndValues ...interface{})
+	Info(msg string, keysAndValues ...interface{})
+	Warn(msg string, keysAndValues ...interface{})
+	Error(msg string, keysAndValues ...interface{})
+}
+
+var _ Logger = (*slog.Logger)(nil)
+
+type Level int
+
+const (
+	LevelDebug Level = iota
+	LevelInfo
+	LevelWarn
+	LevelError
+)
+
+var levelNames = map[Level]string{
+	LevelDebug: "DEBUG",
+	LevelInfo:  "INFO",
+	LevelWarn:  "WARN",
+	LevelError: "ERROR",
+}
+
+var (
+	loggerMu      sync.RWMutex
+	packageLogger Logger = NewStdLogger(log.New(os.Stderr, "", log.LstdFlags), LevelInfo)
+)
+
+// SetLogger redirects all library logging which is not bound to a MongoClient or RestServer
+// with its own Logger. Use NopLogger to silence it.
+func SetLogger(logger Logger) {
+	loggerMu.Lock()
+	defer loggerMu.Unlock()
+	packageLogger = logger
+}
+
+func defaultLogger() Logger {
+	loggerMu.RLock()
+	defer loggerMu.RUnlock()
+	return packageLogger
+}
+
+type stdLogger struct {
+	l     *log.Logger
+	level Level
+}
+
+// NewStdLogger writes log lines such as `INFO msg key=value` to a standard library logger.
+func NewStdLogger(l *log.Logger, level Level) Logger {
+	return stdLogger{l: l, level: level}
+}
+
+func (s stdLogger) log(level Level, msg string, keysAndValues []interface{}) {
+	if level < s.level {
+		return
+	}
+	var buf bytes.Buffer
+	buf.WriteString(levelNames[level])
+	buf.WriteByte(' ')
+	buf.WriteString(msg)
+	for i := 0; i < len(keysAndValues); i += 2 {
+		if i+1 < len(keysAndValues) {
+			fmt.Fprintf(&buf, " %v=%v", keysAndValues[i], keysAndValues[i+1])
+		} else {
+			fmt.Fprintf(&buf, " %v", keysAndValues[i])
+		}
+	}
+	s.l.Println(buf.String())
+}
+
+func (s stdLogger) Debug(msg string, keysAndValues ...interface{}) {
+	s.log(LevelDebug, msg, keysAndValues)
+}
+
+func (s stdLogger) Info(msg string, keysAndValues ...interface{}) {
+	s.log(LevelInfo, msg, keysAndValues)
+}
+
+func (s stdLogger) Warn(msg string, keysAndValues ...interface{}) {
+	s.log(LevelWarn, msg, keysAndValues)
+}
+
+func (s stdLogger) Error(msg string, keysAndValues ...interface{}) {
+	s.log(LevelError, msg, keysAndValues)
+}
+
+type nopLogger struct{}
+
+func NopLogger() Logger {
+	return nopLogger{}
+}
+
+func (nopLogger) Debug(msg string, keysAndValues ...interface{}) {}
+func (nopLogger) Info(msg string, keysAndValues ...interface{})  {}
+func (nopLogger) Warn(msg string, keysAndValues ...interface{})  {}
+func (nopLogger) Error(msg string, keysAndValues ...interface{}) {}
+
+// ZapSugaredLogger is the part of *zap.SugaredLogger used by NewZapLogger.
+type ZapSugaredLogger interface {
+	Debugw(msg string, keysAndValues ...interface{})
+	Infow(msg string, keysAndValues ...interface{})
+	Warnw(msg string, keysAndValues ...interface{})
+	Errorw(msg string, keysAndValues ...interface{})
+}
+
+type zapLogger struct {
+	l ZapSugaredLogger
+}
+
+func NewZapLogger(l ZapSugaredLogger) Logger {
+	return zapLogger{l: l}
+}
+
+func (z zapLogger) Debug(msg string, keysAndValues ...interface{}) {
+	z.l.Debugw(msg, keysAndValues...)
+}
+
+func (z zapLogger) Info(msg string, keysAndValues ...interface{}) {
+	z.l.Infow(msg, keysAndValues...)
+}
+
+func (z zapLogger) Warn(msg string, keysAndValues ...interface{}) {
+	z.l.Warnw(msg, keysAndValues...)
+}
+
+func (z zapLogger) Error(msg string, keysAndValues ...interface{}) {
+	z.l.Errorw(msg, keysAndValues...)
+}
@@ -0,0 +1,198 @@
+package mongo
+
+import (
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// matchFilter evaluates a query filter against a document in memory. It supports equality
+// on dotted paths, the comparison operators $eq, $ne, $gt, $gte, $lt, $lte, $in, $nin and
+// $exists as well as $and, $or and $nor. Unknown operators never match.
+func matchFilter(doc bson.M, filter bson.M) bool {
+	for key, condition := range filter {
+		switch key {
+		case "$and", "$or", "$nor":
+			clauses, ok := asArray(condition)
+			if !ok {
+				return false
+			}
+			matched := 0
+			for _, clause := range clauses {
+				if sub, ok := asMap(clause); ok && matchFilter(doc, sub) {
+					matched++
+				}
+			}
+			if key == "$and" && matched != len(clauses) || key == "$or" && matched == 0 || key == "$nor" && matched > 0 {
+				return false
+			}
+		default:
+			value, found := lookupPath(doc, key)
+			if !matchCondition(value, found, condition) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func matchCondition(value interface{}, found bool, condition interface{}) bool {
+	operators, ok := asMap(condition)
+	if !ok || len(operators) == 0 || !isOperatorMap(operators) {
+		return found && matchValue(value, condition)
+	}
+	for op, operand := range operators {
+		var matched bool
+		switch op {
+		case "$eq":
+			matched = found && matchValue(value, operand)
+		case "$ne":
+			matched = !found || !matchValue(value, operand)
+		case "$gt", "$gte", "$lt", "$lte":
+			c, ok := compareValues(value, operand)
+			matched = found && ok && (op == "$gt" && c > 0 || op == "$gte" && c >= 0 || op == "$lt" && c < 0 || op == "$lte" && c <= 0)
+		case "$in", "$nin":
+			values, _ := asArray(operand)
+			in := false
+			for _, v := range values {
+				if found && matchValue(value, v) {
+					in = true
+					break
+				}
+			}
+			matched = in == (op == "$in")
+		case "$exists":
+			exists, _ := operand.(bool)
+			matched = found == exists
+		}
+		if !matched {
+			return false
+		}
+	}
+	return true
+}
+
+// matchValue compares like MongoDB equality, an array value matches if one of its
+// elements does.
+func matchValue(value, expected interface{}) bool {
+	if c, ok := compareValues(value, expected); ok {
+		return c == 0
+	}
+	if values, ok := asArray(value); ok {
+		if _, isArray := asArray(expected); !isArray {
+			for _, v := range values {
+				if matchValue(v, expected) {
+					return true
+				}
+			}
+			return false
+		}
+	}
+	return reflect.DeepEqual(value, expected)
+}
+
+// compareValues orders numbers, strings and dates, ok is false for other types.
+func compareValues(a, b interface{}) (int, bool) {
+	if x, ok := asFloat(a); ok {
+		if y, ok := asFloat(b); ok {
+			return compareFloats(x, y), true
+		}
+		return 0, false
+	}
+	if x, ok := a.(string); ok {
+		if y, ok := b.(string); ok {
+			return strings.Compare(x, y), true
+		}
+		return 0, false
+	}
+	if x, ok := asTime(a); ok {
+		if y, ok := asTime(b); ok {
+			return compareFloats(float64(x.UnixNano()), float64(y.UnixNano())), true
+		}
+	}
+	return 0, false
+}
+
+func compareFloats(x, y float64) int {
+	switch {
+	case x < y:
+		return -1
+	case x > y:
+		return 1
+	}
+	return 0
+}
+
+func asFloat(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case int:
+		return float64(n), true
+	case int32:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case float64:
+		return n, true
+	case float32:
+		return float64(n), true
+	}
+	return 0, false
+}
+
+func asTime(v interface{}) (time.Time, bool) {
+	switch t := v.(type) {
+	case time.Time:
+		return t, true
+	case primitive.DateTime:
+		return t.Time(), true
+	}
+	return time.Time{}, false
+}
+
+func asMap(v interface{}) (bson.M, bool) {
+	switch m := v.(type) {
+	case bson.M:
+		return m, true
+	case map[string]interface{}:
+		return m, true
+	case bson.D:
+		return m.Map(), true
+	}
+	return nil, false
+}
+
+func asArray(v interface{}) ([]interface{}, bool) {
+	switch a := v.(type) {
+	case bson.A:
+		return a, true
+	case []interface{}:
+		return a, true
+	}
+	return nil, false
+}
+
+func isOperatorMap(m bson.M) bool {
+	for k := range m {
+		if !strings.HasPrefix(k, "$") {
+			return false
+		}
+	}
+	return true
+}
+
+// lookupPath returns the value of a dotted path, e.g. "address.city".
+func lookupPath(doc bson.M, path string) (interface{}, bool) {
+	var current interface{} = doc
+	for _, part := range strings.Split(path, ".") {
+		m, ok := asMap(current)
+		if !ok {
+			return nil, false
+		}
+		if current, ok = m[part]; !ok {
+			return nil, false
+		}
+	}
+	return current, true
+}
@@ -0,0 +1,82 @@
+package mongo
+
+import (
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"time"
+)
+
+// MetadataConfig stamps metadata fields on the documents written to the collections in
+// Namespaces, given as "database.collection" or "database.*". Every field is named by its
+// config value, empty names are not written. CreatedAt and CreatedBy are kept on replaces.
+type MetadataConfig struct {
+	Namespaces []string
+	CreatedAt  string
+	UpdatedAt  string
+	CreatedBy  string
+	UpdatedBy  string
+	ClientIP   string
+	RequestID  string
+}
+
+// DefaultMetadataConfig stamps createdAt, updatedAt, createdBy and updatedBy.
+func DefaultMetadataConfig(namespaces ...string) *MetadataConfig {
+	return &MetadataConfig{
+		Namespaces: namespaces,
+		CreatedAt:  "createdAt",
+		UpdatedAt:  "updatedAt",
+		CreatedBy:  "createdBy",
+		UpdatedBy:  "updatedBy",
+	}
+}
+
+func (b MongoClient) stamps(database, collection string) bool {
+	return b.config != nil && b.config.Metadata != nil && matchNamespace(b.config.Metadata.Namespaces, database, collection)
+}
+
+// currentDocument reads the document matching filter before it is replaced, if the created
+// fields of the collection have to be kept or hooks have to know whether an upsert inserts.
+// Audit and history use the image returned by the write instead, which is atomic with it.
+func (b MongoClient) currentDocument(col *mongo.Collection, filter bson.M) bson.M {
+	database, collection := col.Database().Name(), col.Name()
+	if !b.stamps(database, collection) && len(b.hooksOf(database, collection)) == 0 {
+		return nil
+	}
+	current, _ := findOne(b.Context(), col, filter)
+	return current
+}
+
+// stamp sets the metadata fields of doc. Inserted documents get the created fields, replaced
+// and updated ones take them from before, if given, and never from doc.
+func (b MongoClient) stamp(database, collection string, doc bson.M, inserted bool, before bson.M) {
+	if doc == nil || !b.stamps(database, collection) {
+		return
+	}
+	config := b.config.Metadata
+	ctx := b.Context()
+	now := time.Now()
+	set := func(field string, value interface{}) {
+		if field != "" {
+			doc[field] = value
+		}
+	}
+	if inserted {
+		set(config.CreatedAt, now)
+		set(config.CreatedBy, PrincipalFromContext(ctx))
+	} else {
+		// created fields sent by the caller are dropped, they cannot be forged by an update
+		for _, field := range []string{config.CreatedAt, config.CreatedBy} {
+			if field == "" {
+				continue
+			}
+			delete(doc, field)
+			if value, ok := before[field]; ok {
+				doc[field] = value
+			}
+		}
+	}
+	set(config.UpdatedAt, now)
+	set(config.UpdatedBy, PrincipalFromContext(ctx))
+	set(config.ClientIP, ClientIPFromContext(ctx))
+	set(config.RequestID, RequestIDFromContext(ctx))
+}
@@ -0,0 +1,225 @@
+package mongo
+
+import (
+	"context"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/event"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	defaultMetricsPath = "/metrics"
+	// metricsNamespace prefixes all metric names, so they do not collide with metrics of a
+	// service embedding the handler
+	metricsNamespace = "mongoclient"
+)
+
+var (
+	registerMetricsOnce sync.Once
+
+	httpRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "http_requests_total",
+		Help:      "Number of HTTP requests by route template, method and status code.",
+	}, []string{"route", "method", "code"})
+	httpDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: metricsNamespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "Latency of HTTP requests by route template and method.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"route", "method"})
+
+	mongoCommandDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: metricsNamespace,
+		Name:      "command_duration_seconds",
+		Help:      "Latency of MongoDB commands by database, collection and command.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"database", "collection", "command"})
+	mongoCommandErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "command_errors_total",
+		Help:      "Number of failed MongoDB commands by database, collection and command.",
+	}, []string{"database", "collection", "command"})
+	mongoPoolCheckouts = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "pool_checkouts_total",
+		Help:      "Number of connection checkouts by server address and result.",
+	}, []string{"address", "result"})
+	mongoPoolSize = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "pool_connections",
+		Help:      "Number of open connections by server address.",
+	}, []string{"address"})
+	mongoPoolInUse = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "pool_connections_in_use",
+		Help:      "Number of checked out connections by server address.",
+	}, []string{"address"})
+)
+
+// registerMetrics registers the metrics with the default registry. A collision with a metric
+// of the embedding service is logged instead of panicking.
+func registerMetrics() {
+	registerMetricsOnce.Do(func() {
+		for _, c := range []prometheus.Collector{httpRequests, httpDuration, mongoCommandDuration, mongoCommandErrors,
+			mongoPoolCheckouts, mongoPoolSize, mongoPoolInUse} {
+			if err := prometheus.Register(c); err != nil {
+				defaultLogger().Warn("registering metric failed", "error", err)
+			}
+		}
+	})
+}
+
+func MetricsRoute(path string) Route {
+	if path == "" {
+		path = defaultMetricsPath
+	}
+	return Route{Path: path, HandlerFc: promhttp.Handler().ServeHTTP, Methods: "GET", Group: RouteGroupAdmin}
+}
+
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *statusRecorder) Write(b []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	return r.ResponseWriter.Write(b)
+}
+
+func (r *statusRecorder) Flush() {
+	if f, ok := r.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (r *statusRecorder) Status() int {
+	if r.status == 0 {
+		return http.StatusOK
+	}
+	return r.status
+}
+
+func instrumentRoute(route string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		recorder := &statusRecorder{ResponseWriter: w}
+		next.ServeHTTP(recorder, r)
+		httpDuration.WithLabelValues(route, r.Method).Observe(time.Since(start).Seconds())
+		httpRequests.WithLabelValues(route, r.Method, strconv.Itoa(recorder.Status())).Inc()
+	})
+}
+
+type commandInfo struct {
+	database   string
+	collection string
+	command    string
+}
+
+func newCommandInfo(e *event.CommandStartedEvent) commandInfo {
+	return commandInfo{
+		database:   e.DatabaseName,
+		collection: commandCollection(e.Command, e.CommandName),
+		command:    e.CommandName,
+	}
+}
+
+// commandCollection returns the collection a command operates on, which is the value of
+// the command name element for all CRUD commands, e.g. {"find": "collection", ...}.
+func commandCollection(cmd bson.Raw, name string) string {
+	value, err := cmd.LookupErr(name)
+	if err != nil {
+		return ""
+	}
+	collection, ok := value.StringValueOK()
+	if !ok {
+		return ""
+	}
+	return collection
+}
+
+func newMetricsCommandMonitor() *event.CommandMonitor {
+	var started sync.Map
+	finished := func(requestID int64, duration time.Duration, failed bool) {
+		v, ok := started.Load(requestID)
+		if !ok {
+			return
+		}
+		started.Delete(requestID)
+		info := v.(commandInfo)
+		mongoCommandDuration.WithLabelValues(info.database, info.collection, info.command).Observe(duration.Seconds())
+		if failed {
+			mongoCommandErrors.WithLabelValues(info.database, info.collection, info.command).Inc()
+		}
+	}
+	return &event.CommandMonitor{
+		Started: func(ctx context.Context, e *event.CommandStartedEvent) {
+			started.Store(e.RequestID, newCommandInfo(e))
+		},
+		Succeeded: func(ctx context.Context, e *event.CommandSucceededEvent) {
+			finished(e.RequestID, time.Duration(e.DurationNanos), false)
+		},
+		Failed: func(ctx context.Context, e *event.CommandFailedEvent) {
+			finished(e.RequestID, time.Duration(e.DurationNanos), true)
+		},
+	}
+}
+
+func newMetricsPoolMonitor() *event.PoolMonitor {
+	return &event.PoolMonitor{
+		Event: func(e *event.PoolEvent) {
+			switch e.Type {
+			case event.ConnectionCreated:
+				mongoPoolSize.WithLabelValues(e.Address).Inc()
+			case event.ConnectionClosed:
+				mongoPoolSize.WithLabelValues(e.Address).Dec()
+			case event.GetSucceeded:
+				mongoPoolCheckouts.WithLabelValues(e.Address, "success").Inc()
+				mongoPoolInUse.WithLabelValues(e.Address).Inc()
+			case event.GetFailed:
+				mongoPoolCheckouts.WithLabelValues(e.Address, "failed").Inc()
+			case event.ConnectionReturned:
+				mongoPoolInUse.WithLabelValues(e.Address).Dec()
+			}
+		},
+	}
+}
+
+// combineCommandMonitors fans the driver events out to all monitors, the driver accepts only one.
+func combineCommandMonitors(monitors []*event.CommandMonitor) *event.CommandMonitor {
+	return &event.CommandMonitor{
+		Started: func(ctx context.Context, e *event.CommandStartedEvent) {
+			for _, m := range monitors {
+				if m.Started != nil {
+					m.Started(ctx, e)
+				}
+			}
+		},
+		Succeeded: func(ctx context.Context, e *event.CommandSucceededEvent) {
+			for _, m := range monitors {
+				if m.Succeeded != nil {
+					m.Succeeded(ctx, e)
+				}
+			}
+		},
+		Failed: func(ctx context.Context, e *event.CommandFailedEvent) {
+			for _, m := range monitors {
+				if m.Failed != nil {
+					m.Failed(ctx, e)
+				}
+			}
+		},
+	}
+}
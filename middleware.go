@@ -0,0 +1,131 @@
+package mongo
+
+import (
+	"compress/gzip"
+	"context"
+	"fmt"
+	"net/http"
+	"runtime/debug"
+	"strings"
+	"time"
+)
+
+// Middleware wraps a handler, e.g. to authenticate requests.
+type Middleware func(http.Handler) http.Handler
+
+// Chain wraps handler in middleware, the first middleware is the outermost.
+func Chain(handler http.Handler, middleware ...Middleware) http.Handler {
+	for i := len(middleware) - 1; i >= 0; i-- {
+		handler = middleware[i](handler)
+	}
+	return handler
+}
+
+// Recover answers panics of the handler with 500 and logs them with their stack. Streaming
+// handlers abort responses with http.ErrAbortHandler, which is passed on to the server.
+func Recover(logger Logger) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			recorder := &statusRecorder{ResponseWriter: w}
+			defer func() {
+				v := recover()
+				if v == nil {
+					return
+				}
+				if v == http.ErrAbortHandler {
+					panic(v)
+				}
+				err := fmt.Errorf("panic: %v", v)
+				if log := requestLogFromContext(r.Context()); log != nil {
+					log.record(err, "stack", string(debug.Stack()))
+				} else {
+					logger.Error(err.Error(), "requestId", RequestIDFromContext(r.Context()), "stack", string(debug.Stack()))
+				}
+				if recorder.status != 0 {
+					// the response has started, aborting is the only way to tell the client
+					panic(http.ErrAbortHandler)
+				}
+				http.Error(recorder, "InternalServerError", http.StatusInternalServerError)
+			}()
+			next.ServeHTTP(recorder, r)
+		})
+	}
+}
+
+// Timeout cancels the request context after d, so that database operations of the request
+// fail. Unlike http.TimeoutHandler it does not buffer the response, streaming is unaffected.
+func Timeout(d time.Duration) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx, cancel := context.WithTimeout(r.Context(), d)
+			defer cancel()
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// Gzip compresses responses for clients accepting gzip encoding.
+func Gzip(level int) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Add("Vary", "Accept-Encoding")
+			if !strings.Contains(r.Header.Get("Accept-Encoding"), "gzip") || r.Method == http.MethodHead {
+				next.ServeHTTP(w, r)
+				return
+			}
+			gz, err := gzip.NewWriterLevel(w, level)
+			if err != nil {
+				gz = gzip.NewWriter(w)
+			}
+			writer := &gzipResponseWriter{ResponseWriter: w, gz: gz}
+			defer writer.close()
+			next.ServeHTTP(writer, r)
+		})
+	}
+}
+
+type gzipResponseWriter struct {
+	http.ResponseWriter
+	gz         *gzip.Writer
+	headerSent bool
+	compressed bool
+}
+
+func (g *gzipResponseWriter) WriteHeader(status int) {
+	if g.headerSent {
+		return
+	}
+	g.headerSent = true
+	if status >= http.StatusOK && status != http.StatusNoContent && status != http.StatusNotModified {
+		g.compressed = true
+		g.Header().Del("Content-Length")
+		g.Header().Set("Content-Encoding", "gzip")
+	}
+	g.ResponseWriter.WriteHeader(status)
+}
+
+func (g *gzipResponseWriter) Write(b []byte) (int, error) {
+	if !g.headerSent {
+		g.WriteHeader(http.StatusOK)
+	}
+	if !g.compressed {
+		return g.ResponseWriter.Write(b)
+	}
+	return g.gz.Write(b)
+}
+
+func (g *gzipResponseWriter) Flush() {
+	if g.compressed {
+		g.gz.Flush()
+	}
+	if f, ok := g.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// close finishes the gzip stream, responses without body are left uncompressed.
+func (g *gzipResponseWriter) close() {
+	if g.compressed {
+		g.gz.Close()
+	}
+}
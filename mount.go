@@ -0,0 +1,236 @@
+package mongo
+
+import (
+	"github.com/gorilla/mux"
+	"net"
+	"net/http"
+)
+
+// Route groups, used to enable or disable routes by what they do.
+const (
+	RouteGroupRead   = "read"
+	RouteGroupWrite  = "write"
+	RouteGroupDrop   = "drop"
+	RouteGroupAdmin  = "admin"
+	RouteGroupHealth = "health"
+
+	defaultSearchSegment = "search"
+)
+
+// Segments are the reserved path segments of the namespace routes, e.g.
+// /{database}/{collection}/search. Empty segments keep their default.
+type Segments struct {
+	Search    string
+	Bulk      string
+	Query     string
+	Aggregate string
+	Count     string
+	Distinct  string
+	History   string
+	Restore   string
+	Trash     string
+}
+
+func DefaultSegments() Segments {
+	return Segments{
+		Search:    defaultSearchSegment,
+		Bulk:      "_bulk",
+		Query:     "_query",
+		Aggregate: "_aggregate",
+		Count:     "_count",
+		Distinct:  "_distinct",
+		History:   "_history",
+		Restore:   "_restore",
+		Trash:     "_trash",
+	}
+}
+
+func (s Segments) withDefaults() Segments {
+	defaults := DefaultSegments()
+	if s.Search == "" {
+		s.Search = defaults.Search
+	}
+	if s.Bulk == "" {
+		s.Bulk = defaults.Bulk
+	}
+	if s.Query == "" {
+		s.Query = defaults.Query
+	}
+	if s.Aggregate == "" {
+		s.Aggregate = defaults.Aggregate
+	}
+	if s.Count == "" {
+		s.Count = defaults.Count
+	}
+	if s.Distinct == "" {
+		s.Distinct = defaults.Distinct
+	}
+	if s.History == "" {
+		s.History = defaults.History
+	}
+	if s.Restore == "" {
+		s.Restore = defaults.Restore
+	}
+	if s.Trash == "" {
+		s.Trash = defaults.Trash
+	}
+	return s
+}
+
+// HandlerOptions configure the handler returned by NewHandler. Routes are registered before
+// the built-in routes, so they can override them. Middleware wraps every route, the first
+// middleware is the outermost. Panics are recovered with a 500 response. X-Forwarded-For is
+// only honoured for the addresses or CIDR ranges in TrustedProxies. RateLimit and Metrics
+// add the quota and metrics routes.
+type HandlerOptions struct {
+	PathPrefix     string
+	ReadOnly       bool
+	NoDrops        bool
+	NoAdmin        bool
+	Segments       Segments
+	Routes         []Route
+	Middleware     []Middleware
+	Principal      PrincipalFunc
+	Envelope       string
+	Logger         Logger
+	SafeMode       *SafeModeConfig
+	TrustedProxies []string
+	MaxBodySize    int64
+	RateLimit      *RateLimitConfig
+	Metrics        bool
+	MetricsPath    string
+	AccessLog      bool
+}
+
+func (o HandlerOptions) enabled(route Route) bool {
+	switch route.Group {
+	case RouteGroupWrite:
+		return !o.ReadOnly
+	case RouteGroupDrop:
+		return !o.ReadOnly && !o.NoDrops
+	case RouteGroupAdmin:
+		return !o.NoAdmin
+	}
+	return true
+}
+
+// routes returns the custom and the built-in routes.
+func (o HandlerOptions) routes(backend *MongoClient) []Route {
+	builtin := append(HealthRoutes(backend), AdminRoutes(backend)...)
+	builtin = append(builtin, namespaceRoutes(backend, o.Segments.withDefaults())...)
+	routes := make([]Route, 0, len(o.Routes)+len(builtin))
+	routes = append(routes, o.Routes...)
+	return append(routes, builtin...)
+}
+
+func (o HandlerOptions) logger() Logger {
+	if o.Logger != nil {
+		return o.Logger
+	}
+	return defaultLogger()
+}
+
+func (o HandlerOptions) principal() PrincipalFunc {
+	if o.Principal != nil {
+		return o.Principal
+	}
+	return BasicAuthPrincipal
+}
+
+// routeStack registers routes and wraps their handlers, the same way for RestServer and
+// NewHandler.
+type routeStack struct {
+	options        HandlerOptions
+	logger         Logger
+	principal      PrincipalFunc
+	trustedProxies []*net.IPNet
+	limiter        *RateLimiter
+	safeMode       *safeMode
+	tracing        bool
+}
+
+func newRouteStack(opts HandlerOptions) *routeStack {
+	s := &routeStack{options: opts, logger: opts.logger(), principal: opts.principal()}
+	s.trustedProxies = parseTrustedProxies(opts.TrustedProxies, s.logger)
+	if opts.RateLimit != nil {
+		// only a configured PrincipalFunc is trusted to key the buckets
+		s.limiter = NewRateLimiter(*opts.RateLimit, opts.Principal)
+	}
+	if opts.SafeMode != nil {
+		s.safeMode = newSafeMode(*opts.SafeMode, s.principal, s.logger)
+	}
+	if opts.Metrics {
+		registerMetrics()
+	}
+	return s
+}
+
+// router registers the metrics and quota routes, if enabled, and the routes of the enabled
+// groups.
+func (s *routeStack) router(routes []Route) *mux.Router {
+	var builtin []Route
+	if s.options.Metrics {
+		builtin = append(builtin, MetricsRoute(s.options.MetricsPath))
+	}
+	if s.limiter != nil {
+		builtin = append(builtin, s.limiter.QuotaRoute())
+	}
+	r := mux.NewRouter().UseEncodedPath()
+	for _, group := range [][]Route{builtin, routes} {
+		for _, route := range group {
+			if !s.options.enabled(route) {
+				continue
+			}
+			s.logger.Debug("adding route", "path", route.Path+route.PathPrefix, "methods", route.Methods)
+			registerRoute(r, route, s.handler(route))
+		}
+	}
+	return r
+}
+
+// handler wraps the handler of a route, from the outermost: request context, access log,
+// tracing, metrics, panic recovery, HandlerOptions.Middleware, rate limit, safe mode of
+// drops, Route.Middleware, the read-only mark and the body size limit.
+func (s *routeStack) handler(route Route) http.Handler {
+	var handler http.Handler
+	handler = route.HandlerFc
+	if s.options.MaxBodySize > 0 {
+		handler = limitBody(s.options.MaxBodySize, handler)
+	}
+	if s.options.ReadOnly {
+		handler = withReadOnly(handler)
+	}
+	handler = Chain(handler, route.Middleware...)
+	if s.safeMode != nil && route.Group == RouteGroupDrop {
+		handler = s.safeMode.Middleware(handler)
+	}
+	// probes of orchestrators must not be throttled into restarting the server
+	if s.limiter != nil && route.Group != RouteGroupHealth {
+		handler = s.limiter.Handler(route, handler)
+	}
+	handler = Chain(handler, s.options.Middleware...)
+	handler = Recover(s.logger)(handler)
+	if s.options.Metrics {
+		handler = instrumentRoute(route.Path+route.PathPrefix, handler)
+	}
+	if s.tracing {
+		handler = traceRoute(route.Path+route.PathPrefix, handler)
+	}
+	if s.options.AccessLog {
+		handler = accessLog(s.logger, route.Path+route.PathPrefix, handler)
+	}
+	return withRequestContext(s.principal, s.options.Envelope, s.logger, s.trustedProxies, handler)
+}
+
+// NewHandler returns the REST API as http.Handler, to be mounted in an existing server,
+// e.g. mux.Handle("/api/", mongo.NewHandler(client, &mongo.HandlerOptions{PathPrefix: "/api"})).
+func NewHandler(backend *MongoClient, opts *HandlerOptions) http.Handler {
+	if opts == nil {
+		opts = &HandlerOptions{}
+	}
+	r := newRouteStack(*opts).router(opts.routes(backend))
+	if opts.PathPrefix != "" {
+		return http.StripPrefix(opts.PathPrefix, r)
+	}
+	return r
+}
@@ -0,0 +1,158 @@
+package mongo
+
+import (
+	"errors"
+	"fmt"
+	"github.com/gorilla/mux"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+const (
+	// path variables match a single, possibly percent encoded, segment. Names are
+	// validated by withNamespace, so invalid names are answered with 400 instead of 404.
+	databaseVar   = "{database:[^/]+}"
+	collectionVar = "{collection:[^/]+}"
+	documentVar   = "{document:[^/]+}"
+
+	maxDatabaseNameLength  = 63
+	maxNamespaceLength     = 255
+	databaseInvalidChars   = "/\\. \"$*<>:|?\x00"
+	systemCollectionPrefix = "system."
+)
+
+var (
+	errInvalidNamespace  = errors.New("invalid namespace")
+	errReservedNamespace = errors.New("namespace is reserved")
+)
+
+// ValidateNamespace checks database and collection names against the MongoDB naming rules,
+// an empty collection only validates the database. system.* collections are reserved.
+func ValidateNamespace(database, collection string) error {
+	if database == "" || len(database) > maxDatabaseNameLength || strings.ContainsAny(database, databaseInvalidChars) {
+		return fmt.Errorf("%w: database %q", errInvalidNamespace, database)
+	}
+	if collection == "" {
+		return nil
+	}
+	if strings.ContainsAny(collection, "$\x00") || len(database)+1+len(collection) > maxNamespaceLength {
+		return fmt.Errorf("%w: collection %q", errInvalidNamespace, collection)
+	}
+	if strings.HasPrefix(collection, systemCollectionPrefix) {
+		return fmt.Errorf("%w: %s.%s", errReservedNamespace, database, collection)
+	}
+	return nil
+}
+
+// matchNamespace reports whether one of the patterns, "database.collection" or
+// "database.*", matches the namespace.
+func matchNamespace(patterns []string, database, collection string) bool {
+	for _, pattern := range patterns {
+		if pattern == database+"."+collection || pattern == database+".*" {
+			return true
+		}
+	}
+	return false
+}
+
+// internalNamespace reports whether the collection is written by the client itself: the
+// webhook subscriptions, deliveries and dead letters, the audit log, the slow query log and
+// the revisions of versioned collections. Only enabled features reserve their collections.
+func (b MongoClient) internalNamespace(database, collection string) bool {
+	if b.config == nil {
+		return false
+	}
+	var namespaces []string
+	if c := b.config.Webhooks; c != nil && c.Store == nil {
+		subscriptions := orDefault(c.Database, defaultWebhookDatabase) + "." + orDefault(c.Collection, defaultWebhookCollection)
+		namespaces = append(namespaces, subscriptions, subscriptions+webhookDeliveriesSuffix, subscriptions+webhookDeadLettersSuffix)
+	}
+	if c := b.config.Audit; c != nil && c.Sink == nil {
+		namespaces = append(namespaces, orDefault(c.Database, defaultAuditDatabase)+"."+orDefault(c.Collection, defaultAuditCollection))
+	}
+	if c := b.config.SlowQuery; c != nil {
+		namespaces = append(namespaces, orDefault(c.Database, defaultSlowQueryDatabase)+"."+orDefault(c.Collection, defaultSlowQueryCollection))
+	}
+	if matchNamespace(namespaces, database, collection) {
+		return true
+	}
+	if b.config.History == nil {
+		return false
+	}
+	suffix := b.config.History.suffix()
+	return strings.HasSuffix(collection, suffix) && b.versioned(database, strings.TrimSuffix(collection, suffix))
+}
+
+func orDefault(value, defaultValue string) string {
+	if value == "" {
+		return defaultValue
+	}
+	return value
+}
+
+// withNamespace decodes the path variables of a route and validates the namespace, the
+// same way for all verbs. Internal collections of the client are rejected.
+func withNamespace(mongoClient *MongoClient, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		vars := mux.Vars(r)
+		decoded := make(map[string]string, len(vars))
+		for k, v := range vars {
+			value, err := url.PathUnescape(v)
+			if checkError(err, w, r) {
+				return
+			}
+			decoded[k] = value
+		}
+		if database, ok := decoded["database"]; ok {
+			collection := decoded["collection"]
+			if checkError(ValidateNamespace(database, collection), w, r) {
+				return
+			}
+			if collection != "" && mongoClient.internalNamespace(database, collection) {
+				checkError(fmt.Errorf("%w: %s.%s", errReservedNamespace, database, collection), w, r)
+				return
+			}
+		}
+		next(w, mux.SetURLVars(r, decoded))
+	}
+}
+
+// documentID parses the document segment of a path. Extended JSON, e.g. {"$oid": "..."} or
+// {"$numberLong": "1"}, selects the exact type, anything else is used as string.
+func documentID(document string) (interface{}, error) {
+	if !strings.HasPrefix(document, "{") {
+		return document, nil
+	}
+	var doc bson.D
+	if err := bson.UnmarshalExtJSON([]byte(`{"v":`+document+`}`), false, &doc); err != nil {
+		return nil, fmt.Errorf("%w: document id %s", errInvalidNamespace, document)
+	}
+	return doc[0].Value, nil
+}
+
+// documentFilter matches the document of a path segment. Without extended JSON the
+// segment matches string IDs as well as ObjectIDs and numbers with the same text.
+func documentFilter(document string) (bson.M, error) {
+	if strings.HasPrefix(document, "{") {
+		id, err := documentID(document)
+		if err != nil {
+			return nil, err
+		}
+		return bson.M{documentIDField: id}, nil
+	}
+	ids := bson.A{document}
+	if id, err := primitive.ObjectIDFromHex(document); err == nil {
+		ids = append(ids, id)
+	}
+	if n, err := strconv.ParseInt(document, 10, 64); err == nil {
+		ids = append(ids, n)
+	}
+	if len(ids) == 1 {
+		return bson.M{documentIDField: document}, nil
+	}
+	return bson.M{documentIDField: bson.M{"$in": ids}}, nil
+}
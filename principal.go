@@ -0,0 +1,138 @@
+package mongo
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"strings"
+)
+
+type PrincipalFunc func(r *http.Request) string
+
+// BasicAuthPrincipal is the default PrincipalFunc, it identifies callers by their basic auth user.
+func BasicAuthPrincipal(r *http.Request) string {
+	user, _, ok := r.BasicAuth()
+	if !ok {
+		return ""
+	}
+	return user
+}
+
+// clientIP returns the address of the caller. X-Forwarded-For is only honoured for requests
+// of trusted proxies, the client is the rightmost address which is not a trusted proxy.
+func clientIP(r *http.Request, trustedProxies []*net.IPNet) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	if !trusted(host, trustedProxies) {
+		return host
+	}
+	forwarded := strings.Split(r.Header.Get("X-Forwarded-For"), ",")
+	for i := len(forwarded) - 1; i >= 0; i-- {
+		ip := strings.TrimSpace(forwarded[i])
+		if ip == "" {
+			break
+		}
+		host = ip
+		if !trusted(ip, trustedProxies) {
+			break
+		}
+	}
+	return host
+}
+
+func trusted(host string, trustedProxies []*net.IPNet) bool {
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return false
+	}
+	for _, network := range trustedProxies {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseTrustedProxies parses addresses and CIDR ranges, invalid entries are logged and
+// ignored.
+func parseTrustedProxies(proxies []string, logger Logger) []*net.IPNet {
+	result := make([]*net.IPNet, 0, len(proxies))
+	for _, proxy := range proxies {
+		if !strings.Contains(proxy, "/") {
+			if ip := net.ParseIP(proxy); ip != nil && ip.To4() != nil {
+				proxy += "/32"
+			} else {
+				proxy += "/128"
+			}
+		}
+		_, network, err := net.ParseCIDR(proxy)
+		if err != nil {
+			logger.Warn("ignoring invalid trusted proxy", "proxy", proxy, "error", err)
+			continue
+		}
+		result = append(result, network)
+	}
+	return result
+}
+
+type contextKey int
+
+const (
+	principalKey contextKey = iota
+	requestIDKey
+	responseMetaKey
+	clientIPKey
+	readOnlyKey
+	requestLogKey
+)
+
+func WithPrincipal(ctx context.Context, principal string) context.Context {
+	return context.WithValue(ctx, principalKey, principal)
+}
+
+func PrincipalFromContext(ctx context.Context) string {
+	principal, _ := ctx.Value(principalKey).(string)
+	return principal
+}
+
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDKey, requestID)
+}
+
+func RequestIDFromContext(ctx context.Context) string {
+	requestID, _ := ctx.Value(requestIDKey).(string)
+	return requestID
+}
+
+func WithClientIP(ctx context.Context, ip string) context.Context {
+	return context.WithValue(ctx, clientIPKey, ip)
+}
+
+func ClientIPFromContext(ctx context.Context) string {
+	ip, _ := ctx.Value(clientIPKey).(string)
+	return ip
+}
+
+// withReadOnly marks the requests of a read-only server, routes of the read group must not
+// write then, e.g. by aggregation stages.
+func withReadOnly(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), readOnlyKey, true)))
+	})
+}
+
+func isReadOnly(ctx context.Context) bool {
+	readOnly, _ := ctx.Value(readOnlyKey).(bool)
+	return readOnly
+}
+
+// requestClientIP returns the IP resolved by the server, or the remote address for requests
+// handled outside of it.
+func requestClientIP(r *http.Request) string {
+	if ip := ClientIPFromContext(r.Context()); ip != "" {
+		return ip
+	}
+	return clientIP(r, nil)
+}
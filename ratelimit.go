@@ -0,0 +1,179 @@
+package mongo
+
+import (
+	"container/list"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	RateLimitByPrincipal = "principal"
+	RateLimitByIP        = "ip"
+	RateLimitByRoute     = "route"
+
+	defaultQuotaPath = adminPath + "/quotas"
+	maxBuckets       = 10000
+)
+
+// RateLimitConfig configures a token bucket per key. Rate is the number of tokens refilled
+// per second, Burst the bucket size. Every request takes Route.Cost tokens (at least 1).
+// KeyBy principal needs a PrincipalFunc which verifies the caller, without one requests are
+// keyed by IP.
+type RateLimitConfig struct {
+	Rate      float64
+	Burst     int
+	KeyBy     string
+	QuotaPath string
+}
+
+type Quota struct {
+	Key      string    `bson:"key"`
+	Tokens   float64   `bson:"tokens"`
+	Allowed  uint64    `bson:"allowed"`
+	Rejected uint64    `bson:"rejected"`
+	LastSeen time.Time `bson:"lastSeen"`
+}
+
+type bucket struct {
+	key      string
+	element  *list.Element
+	tokens   float64
+	last     time.Time
+	allowed  uint64
+	rejected uint64
+}
+
+type RateLimiter struct {
+	mu        sync.Mutex
+	config    RateLimitConfig
+	principal PrincipalFunc
+	buckets   map[string]*bucket
+	recent    *list.List
+	now       func() time.Time
+}
+
+func NewRateLimiter(config RateLimitConfig, principal PrincipalFunc) *RateLimiter {
+	if config.Burst < 1 {
+		config.Burst = 1
+	}
+	if config.KeyBy == "" {
+		config.KeyBy = RateLimitByIP
+	}
+	if config.QuotaPath == "" {
+		config.QuotaPath = defaultQuotaPath
+	}
+	return &RateLimiter{
+		config:    config,
+		principal: principal,
+		buckets:   map[string]*bucket{},
+		recent:    list.New(),
+		now:       time.Now,
+	}
+}
+
+func (l *RateLimiter) key(route Route, r *http.Request) string {
+	switch l.config.KeyBy {
+	case RateLimitByRoute:
+		return r.Method + " " + route.Path + route.PathPrefix
+	case RateLimitByPrincipal:
+		// an unverified principal, e.g. any basic auth user, would get a new bucket per name
+		if l.principal == nil {
+			break
+		}
+		if principal := l.principal(r); principal != "" {
+			return "principal:" + principal
+		}
+	}
+	return "ip:" + requestClientIP(r)
+}
+
+// Take removes cost tokens from the bucket of key. If the bucket holds too few tokens
+// nothing is taken and the time until enough tokens are available is returned.
+func (l *RateLimiter) Take(key string, cost int) (bool, time.Duration) {
+	if cost < 1 {
+		cost = 1
+	}
+	if cost > l.config.Burst {
+		cost = l.config.Burst
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := l.now()
+	b, ok := l.buckets[key]
+	if ok {
+		l.recent.MoveToFront(b.element)
+	} else {
+		if len(l.buckets) >= maxBuckets {
+			l.evict()
+		}
+		b = &bucket{key: key, tokens: float64(l.config.Burst), last: now}
+		b.element = l.recent.PushFront(b)
+		l.buckets[key] = b
+	}
+	b.tokens = math.Min(float64(l.config.Burst), b.tokens+now.Sub(b.last).Seconds()*l.config.Rate)
+	b.last = now
+	if b.tokens < float64(cost) {
+		b.rejected++
+		if l.config.Rate <= 0 {
+			return false, time.Hour
+		}
+		return false, time.Duration((float64(cost) - b.tokens) / l.config.Rate * float64(time.Second))
+	}
+	b.tokens -= float64(cost)
+	b.allowed++
+	return true, 0
+}
+
+// evict drops the least recently used bucket, so the number of buckets stays bounded.
+func (l *RateLimiter) evict() {
+	oldest := l.recent.Back()
+	if oldest == nil {
+		return
+	}
+	l.recent.Remove(oldest)
+	delete(l.buckets, oldest.Value.(*bucket).key)
+}
+
+func (l *RateLimiter) Handler(route Route, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ok, retryAfter := l.Take(l.key(route, r), route.Cost)
+		if !ok {
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+			http.Error(w, "TooManyRequests", http.StatusTooManyRequests)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (l *RateLimiter) Quotas() []Quota {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := l.now()
+	quotas := make([]Quota, 0, len(l.buckets))
+	for key, b := range l.buckets {
+		quotas = append(quotas, Quota{
+			Key:      key,
+			Tokens:   math.Min(float64(l.config.Burst), b.tokens+now.Sub(b.last).Seconds()*l.config.Rate),
+			Allowed:  b.allowed,
+			Rejected: b.rejected,
+			LastSeen: b.last,
+		})
+	}
+	sort.Slice(quotas, func(i, j int) bool { return quotas[i].Key < quotas[j].Key })
+	return quotas
+}
+
+func (l *RateLimiter) QuotaRoute() Route {
+	return Route{Path: l.config.QuotaPath, HandlerFc: getQuotas(l), Methods: "GET", Group: RouteGroupAdmin}
+}
+
+func getQuotas(l *RateLimiter) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		writeResponse(w, r, l.Quotas())
+	}
+}
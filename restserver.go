@@ -3,18 +3,31 @@ package mongo
 import (
 	"context"
 	"crypto/tls"
+	"errors"
 	"github.com/gorilla/mux"
 	"github.com/rs/cors"
-	"log"
+	"io"
+	"net"
 	"net/http"
+	"os"
+	"os/signal"
 	"strings"
+	"sync"
+	"syscall"
 	"time"
 )
 
 type RestServer struct {
-	r      *mux.Router
-	srv    *http.Server
-	config ServerConfig
+	r            *mux.Router
+	srv          *http.Server
+	config       ServerConfig
+	ctx          context.Context
+	cancel       context.CancelFunc
+	shuttingDown chan struct{}
+	shutdownOnce sync.Once
+	stopTracing  func(context.Context) error
+	logger       Logger
+	stack        *routeStack
 }
 
 type ServerConfig struct {
@@ -23,6 +36,21 @@ type ServerConfig struct {
 	CertFile, KeyFile         string
 	ReadTimeout, WriteTimeout time.Duration
 	TlsConfig                 *tls.Config
+	MaxBodySize               int64
+	Cors                      *CorsConfig
+	RateLimit                 *RateLimitConfig
+	Principal                 PrincipalFunc
+	ShutdownGracePeriod       time.Duration
+	Metrics                   bool
+	MetricsPath               string
+	Tracing                   *TracingConfig
+	Logger                    Logger
+	AccessLog                 bool
+	Envelope                  string
+	Middleware                []Middleware
+	ReadOnly                  bool
+	SafeMode                  *SafeModeConfig
+	TrustedProxies            []string
 }
 
 type Route struct {
@@ -30,66 +58,165 @@ type Route struct {
 	PathPrefix string
 	HandlerFc  http.HandlerFunc
 	Methods    string
+	Cost       int
+	Group      string
+	Middleware []Middleware
 }
 
+const defaultShutdownGracePeriod = 30 * time.Second
+
 /*****************
 	REST Server
  *****************/
-func RunRestServer(routes []Route, pathPrefix string, cors bool, restConfig ServerConfig) {
+// RunRestServer serves until the listener fails or SIGINT/SIGTERM is received, then drains
+// in-flight requests for the configured grace period and closes the given closers, e.g. the
+// MongoClient.
+func RunRestServer(routes []Route, pathPrefix string, cors bool, restConfig ServerConfig, closers ...io.Closer) {
 	server := NewDefaultServer(routes, restConfig)
-	err := server.Listen(pathPrefix, cors)
-	if err != nil {
-		log.Fatal(err)
+	logger := restConfig.logger()
+	failed := false
+	errs := make(chan error, 1)
+	go func() {
+		errs <- server.Listen(pathPrefix, cors)
+	}()
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(signals)
+	select {
+	case err := <-errs:
+		if !errors.Is(err, http.ErrServerClosed) {
+			logger.Error("listen failed", "error", err)
+			failed = true
+		}
+	case sig := <-signals:
+		logger.Info("shutting down", "signal", sig.String())
 	}
-	err = server.Shutdown(context.Background())
-	if err != nil {
-		log.Fatal(err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), restConfig.shutdownGracePeriod())
+	defer cancel()
+	if err := server.Shutdown(ctx); err != nil {
+		logger.Error("shutdown failed", "error", err)
+		failed = true
 	}
+	for _, closer := range closers {
+		if err := closer.Close(); err != nil {
+			logger.Error("close failed", "error", err)
+			failed = true
+		}
+	}
+	if failed {
+		logger.Error("server stopped with errors")
+		os.Exit(1)
+	}
+	logger.Info("server stopped")
+}
+
+func (c ServerConfig) shutdownGracePeriod() time.Duration {
+	if c.ShutdownGracePeriod <= 0 {
+		return defaultShutdownGracePeriod
+	}
+	return c.ShutdownGracePeriod
+}
+
+func (c ServerConfig) logger() Logger {
+	if c.Logger != nil {
+		return c.Logger
+	}
+	return defaultLogger()
+}
+
+func (c ServerConfig) principal() PrincipalFunc {
+	if c.Principal != nil {
+		return c.Principal
+	}
+	return BasicAuthPrincipal
 }
 
 func NewDefaultServer(routes []Route, config ServerConfig) *RestServer {
-	s := RestServer{}
-	log.Printf("Starting mux router with tls = %v", config.CertFile != "" && config.KeyFile != "")
-	s.r = mux.NewRouter()
-	for _, item := range routes {
-		log.Printf("adding route %s ( %s)", item.Path, item.Methods)
-		if item.Path != "" {
-			s.r.Path(item.Path).HandlerFunc(item.HandlerFc).Methods(strings.Split(item.Methods, ",")...)
-		} else if item.PathPrefix != "" {
-			s.r.PathPrefix(item.PathPrefix).HandlerFunc(item.HandlerFc).Methods(strings.Split(item.Methods, ",")...)
+	s := RestServer{config: config, shuttingDown: make(chan struct{})}
+	s.ctx, s.cancel = context.WithCancel(context.Background())
+	s.srv = &http.Server{
+		Addr:         config.Listen,
+		TLSConfig:    config.TlsConfig,
+		ReadTimeout:  config.ReadTimeout,
+		WriteTimeout: config.WriteTimeout,
+		BaseContext:  func(net.Listener) context.Context { return s.ctx },
+	}
+	// http.Server runs the hooks on every call of Shutdown
+	s.srv.RegisterOnShutdown(func() { s.shutdownOnce.Do(func() { close(s.shuttingDown) }) })
+	s.logger = config.logger()
+	s.logger.Info("starting mux router", "tls", config.CertFile != "" && config.KeyFile != "")
+	s.stack = newRouteStack(config.handlerOptions())
+	if config.Tracing != nil {
+		stopTracing, err := SetupTracing(*config.Tracing)
+		if err != nil {
+			s.logger.Warn("tracing disabled", "error", err)
+		} else {
+			s.stopTracing = stopTracing
+			s.stack.tracing = true
 		}
 	}
-	log.Println("Starting listener")
-	s.config = config
+	s.r = s.stack.router(routes)
 	return &s
 }
 
+// handlerOptions returns the options of the routes, they are wrapped the same way as the
+// ones of NewHandler.
+func (c ServerConfig) handlerOptions() HandlerOptions {
+	return HandlerOptions{
+		ReadOnly:       c.ReadOnly,
+		Middleware:     c.Middleware,
+		Principal:      c.Principal,
+		Envelope:       c.Envelope,
+		Logger:         c.Logger,
+		SafeMode:       c.SafeMode,
+		TrustedProxies: c.TrustedProxies,
+		MaxBodySize:    c.MaxBodySize,
+		RateLimit:      c.RateLimit,
+		Metrics:        c.Metrics,
+		MetricsPath:    c.MetricsPath,
+		AccessLog:      c.AccessLog,
+	}
+}
+
+func registerRoute(r *mux.Router, item Route, handler http.Handler) {
+	if item.Path != "" {
+		r.Path(item.Path).Handler(handler).Methods(strings.Split(item.Methods, ",")...)
+	} else if item.PathPrefix != "" {
+		r.PathPrefix(item.PathPrefix).Handler(handler).Methods(strings.Split(item.Methods, ",")...)
+	}
+}
+
+func limitBody(maxBodySize int64, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.ContentLength > maxBodySize {
+			http.Error(w, "RequestEntityTooLarge", http.StatusRequestEntityTooLarge)
+			return
+		}
+		r.Body = http.MaxBytesReader(w, r.Body, maxBodySize)
+		next.ServeHTTP(w, r)
+	})
+}
+
 func (s *RestServer) Listen(pathPrefix string, corsAllowed bool) error {
 
 	var handler http.Handler
 	handler = s.r
 	if pathPrefix != "" {
 		handler = http.StripPrefix(pathPrefix, handler)
-		log.Printf("path prefix = %s", pathPrefix)
-	}
-	if corsAllowed {
-		log.Println("enable cors")
-		c := cors.New(cors.Options{
-			AllowedOrigins:   []string{"http://localhost:8081"},
-			AllowCredentials: true,
-			AllowedMethods:   []string{"GET", "PUT", "POST", "DELETE"},
-		})
-		handler = c.Handler(handler)
+		s.logger.Info("path prefix", "prefix", pathPrefix)
 	}
-
-	s.srv = &http.Server{
-		Addr:         s.config.Listen,
-		Handler:      handler,
-		TLSConfig:    s.config.TlsConfig,
-		ReadTimeout:  s.config.ReadTimeout,
-		WriteTimeout: s.config.WriteTimeout,
+	if corsAllowed || s.config.Cors != nil {
+		s.logger.Info("enable cors")
+		corsConfig := s.config.Cors
+		if corsConfig == nil {
+			corsConfig = DefaultCorsConfig()
+		}
+		handler = cors.New(corsConfig.options()).Handler(handler)
 	}
 
+	s.srv.Handler = handler
+	s.logger.Info("starting listener", "address", s.config.Listen)
 	if s.config.TlsConfig == nil || s.config.CertFile == "" || s.config.KeyFile == "" {
 		return s.srv.ListenAndServe()
 	} else {
@@ -97,7 +224,30 @@ func (s *RestServer) Listen(pathPrefix string, corsAllowed bool) error {
 	}
 }
 
-func (s RestServer) Shutdown(ctx context.Context) error {
-	s.srv.Shutdown(ctx)
+// ShuttingDown is closed as soon as Shutdown is called, long running handlers such as change
+// streams should select on it and finish their response.
+func (s *RestServer) ShuttingDown() <-chan struct{} {
+	return s.shuttingDown
+}
+
+// Shutdown stops accepting connections and waits for in-flight requests until ctx is done.
+// Requests still running then are cancelled and their connections closed.
+func (s *RestServer) Shutdown(ctx context.Context) error {
+	if s.stopTracing != nil {
+		defer func() {
+			if err := s.stopTracing(ctx); err != nil {
+				s.logger.Error("stopping tracing failed", "error", err)
+			}
+		}()
+	}
+	err := s.srv.Shutdown(ctx)
+	if err != nil {
+		s.cancel()
+		if closeErr := s.srv.Close(); closeErr != nil {
+			s.logger.Error("closing connections failed", "error", closeErr)
+		}
+		return err
+	}
+	s.cancel()
 	return nil
 }
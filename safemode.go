@@ -0,0 +1,110 @@
+package mongo
+
+import (
+	"github.com/gorilla/mux"
+	"math"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+)
+
+const (
+	confirmDropHeader = "X-Confirm-Drop"
+
+	defaultDropRate  = 1.0 / 60
+	defaultDropBurst = 1
+)
+
+// SafeModeConfig guards the routes dropping databases and collections. A drop requires the
+// X-Confirm-Drop header, holding ConfirmToken or, without token, the dropped namespace, e.g.
+// "shop" or "shop.orders". Drops are limited to Rate per second and to databases matching
+// the Databases pattern. Every attempt is logged.
+type SafeModeConfig struct {
+	ConfirmToken string
+	Rate         float64
+	Burst        int
+	Databases    string
+}
+
+type safeMode struct {
+	config    SafeModeConfig
+	databases *regexp.Regexp
+	limiter   *RateLimiter
+	logger    Logger
+	principal PrincipalFunc
+}
+
+func newSafeMode(config SafeModeConfig, principal PrincipalFunc, logger Logger) *safeMode {
+	if config.Rate <= 0 {
+		config.Rate = defaultDropRate
+	}
+	if config.Burst <= 0 {
+		config.Burst = defaultDropBurst
+	}
+	m := &safeMode{
+		config:    config,
+		limiter:   NewRateLimiter(RateLimitConfig{Rate: config.Rate, Burst: config.Burst}, principal),
+		logger:    logger,
+		principal: principal,
+	}
+	if config.Databases != "" {
+		databases, err := regexp.Compile("^(?:" + config.Databases + ")$")
+		if err != nil {
+			// fail closed, an invalid pattern must not allow drops of all databases
+			logger.Error("invalid safe mode database pattern, drops are disabled", "error", err)
+			databases = regexp.MustCompile("$.^")
+		}
+		m.databases = databases
+	}
+	return m
+}
+
+// Middleware guards a drop route, it is added to all routes of RouteGroupDrop.
+func (m *safeMode) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		vars := mux.Vars(r)
+		database, _ := url.PathUnescape(vars["database"])
+		namespace := database
+		if collection, ok := vars["collection"]; ok {
+			collection, _ = url.PathUnescape(collection)
+			namespace += "." + collection
+		}
+		reject := func(status int, reason string) {
+			m.audit(r, namespace, status, reason)
+			http.Error(w, http.StatusText(status), status)
+		}
+		if m.databases != nil && !m.databases.MatchString(database) {
+			reject(http.StatusForbidden, "database not allowed")
+			return
+		}
+		confirmation := r.Header.Get(confirmDropHeader)
+		if m.config.ConfirmToken != "" && confirmation != m.config.ConfirmToken ||
+			m.config.ConfirmToken == "" && confirmation != namespace {
+			reject(http.StatusPreconditionRequired, "missing or wrong confirmation")
+			return
+		}
+		if ok, retryAfter := m.limiter.Take("drop", 1); !ok {
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+			reject(http.StatusTooManyRequests, "drop rate exceeded")
+			return
+		}
+		recorder := &statusRecorder{ResponseWriter: w}
+		next.ServeHTTP(recorder, r)
+		m.audit(r, namespace, recorder.Status(), "")
+	})
+}
+
+func (m *safeMode) audit(r *http.Request, namespace string, status int, reason string) {
+	fields := []interface{}{
+		"requestId", RequestIDFromContext(r.Context()),
+		"principal", m.principal(r),
+		"ip", requestClientIP(r),
+		"namespace", namespace,
+		"status", status,
+	}
+	if reason != "" {
+		fields = append(fields, "reason", reason)
+	}
+	m.logger.Warn("drop attempt", fields...)
+}
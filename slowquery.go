@@ -0,0 +1,323 @@
+package mongo
+
+import (
+	"context"
+	"errors"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/event"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	defaultSlowQueryThreshold  = 100 * time.Millisecond
+	defaultSlowQueryDatabase   = "admin"
+	defaultSlowQueryCollection = "slowqueries"
+	defaultSlowQuerySize       = 16 * 1024 * 1024
+	defaultSlowQueryLimit      = 100
+	defaultSlowQueryQueueSize  = 100
+
+	errCodeNamespaceExists = 48
+	redacted               = "?"
+)
+
+var errSlowQueryLogDisabled = errors.New("slow query log is not enabled")
+
+// queryFields names the element holding the filter or pipeline of each monitored command.
+var queryFields = map[string]string{
+	"find":          "filter",
+	"aggregate":     "pipeline",
+	"count":         "query",
+	"distinct":      "query",
+	"findAndModify": "query",
+	"delete":        "deletes",
+	"update":        "updates",
+}
+
+// SlowQueryConfig enables the slow query log. Operations taking longer than Threshold are
+// stored with their redacted filter shape in a capped collection of CappedSize bytes. Up to
+// QueueSize entries wait to be explained and stored by a single worker, further entries are
+// only logged.
+type SlowQueryConfig struct {
+	Threshold  time.Duration
+	Explain    bool
+	Database   string
+	Collection string
+	CappedSize int64
+	QueueSize  int
+}
+
+type SlowQuery struct {
+	Timestamp   time.Time   `bson:"ts"`
+	Database    string      `bson:"database"`
+	Collection  string      `bson:"collection"`
+	Command     string      `bson:"command"`
+	DurationMs  float64     `bson:"durationMs"`
+	Filter      interface{} `bson:"filter,omitempty"`
+	Error       string      `bson:"error,omitempty"`
+	PlanStages  []string    `bson:"planStages,omitempty"`
+	CollScan    bool        `bson:"collScan"`
+	ExplainFail string      `bson:"explainError,omitempty"`
+}
+
+type slowQueryCommand struct {
+	info    commandInfo
+	command bson.Raw
+}
+
+type slowQueryEntry struct {
+	entry   SlowQuery
+	command bson.Raw
+}
+
+type slowQueryLog struct {
+	config  SlowQueryConfig
+	client  *mongo.Client
+	logger  Logger
+	started sync.Map
+	queue   chan slowQueryEntry
+	dropped int64
+	stop    chan struct{}
+	once    sync.Once
+}
+
+func newSlowQueryLog(config SlowQueryConfig, logger Logger) *slowQueryLog {
+	if config.Threshold <= 0 {
+		config.Threshold = defaultSlowQueryThreshold
+	}
+	if config.Database == "" {
+		config.Database = defaultSlowQueryDatabase
+	}
+	if config.Collection == "" {
+		config.Collection = defaultSlowQueryCollection
+	}
+	if config.CappedSize <= 0 {
+		config.CappedSize = defaultSlowQuerySize
+	}
+	if config.QueueSize <= 0 {
+		config.QueueSize = defaultSlowQueryQueueSize
+	}
+	return &slowQueryLog{config: config, logger: logger, queue: make(chan slowQueryEntry, config.QueueSize), stop: make(chan struct{})}
+}
+
+// init creates the capped collection and starts the worker, it is called once the client
+// is connected.
+func (l *slowQueryLog) init(client *mongo.Client) error {
+	l.client = client
+	err := client.Database(l.config.Database).RunCommand(Ctx(), bson.D{
+		{Key: "create", Value: l.config.Collection},
+		{Key: "capped", Value: true},
+		{Key: "size", Value: l.config.CappedSize},
+	}).Err()
+	if cmdErr, ok := err.(mongo.CommandError); ok && cmdErr.Code == errCodeNamespaceExists {
+		err = nil
+	}
+	if err != nil {
+		return err
+	}
+	go l.run()
+	return nil
+}
+
+func (l *slowQueryLog) collection() *mongo.Collection {
+	return l.client.Database(l.config.Database).Collection(l.config.Collection)
+}
+
+func (l *slowQueryLog) monitor() *event.CommandMonitor {
+	return &event.CommandMonitor{
+		Started: func(ctx context.Context, e *event.CommandStartedEvent) {
+			if _, ok := queryFields[e.CommandName]; !ok {
+				return
+			}
+			info := newCommandInfo(e)
+			if info.database == l.config.Database && info.collection == l.config.Collection {
+				return
+			}
+			l.started.Store(e.RequestID, slowQueryCommand{info: info, command: e.Command})
+		},
+		Succeeded: func(ctx context.Context, e *event.CommandSucceededEvent) {
+			l.finished(e.RequestID, time.Duration(e.DurationNanos), "")
+		},
+		Failed: func(ctx context.Context, e *event.CommandFailedEvent) {
+			l.finished(e.RequestID, time.Duration(e.DurationNanos), e.Failure)
+		},
+	}
+}
+
+func (l *slowQueryLog) finished(requestID int64, duration time.Duration, failure string) {
+	v, ok := l.started.Load(requestID)
+	if !ok {
+		return
+	}
+	l.started.Delete(requestID)
+	if duration < l.config.Threshold || l.client == nil {
+		return
+	}
+	cmd := v.(slowQueryCommand)
+	entry := SlowQuery{
+		Timestamp:  time.Now(),
+		Database:   cmd.info.database,
+		Collection: cmd.info.collection,
+		Command:    cmd.info.command,
+		DurationMs: float64(duration.Microseconds()) / 1000,
+		Filter:     redact(lookup(cmd.command, queryFields[cmd.info.command])),
+		Error:      failure,
+	}
+	l.logger.Warn("slow query", "database", entry.Database, "collection", entry.Collection,
+		"command", entry.Command, "durationMs", entry.DurationMs)
+	// the monitor runs on the goroutine of the operation, explain and insert must not block it
+	// and must not add load while the database is slow already
+	select {
+	case l.queue <- slowQueryEntry{entry: entry, command: cmd.command}:
+	default:
+		l.logger.Warn("slow query log queue is full, entry dropped", "dropped", atomic.AddInt64(&l.dropped, 1))
+	}
+}
+
+func (l *slowQueryLog) run() {
+	for {
+		select {
+		case <-l.stop:
+			return
+		case e := <-l.queue:
+			l.record(e.entry, e.command)
+		}
+	}
+}
+
+func (l *slowQueryLog) close() {
+	l.once.Do(func() { close(l.stop) })
+}
+
+func (l *slowQueryLog) record(entry SlowQuery, command bson.Raw) {
+	if l.config.Explain && entry.Error == "" {
+		cmd, err := explainableCommand(command)
+		var plan bson.M
+		if err == nil {
+			ctx, cancel := context.WithTimeout(Ctx(), timeout)
+			plan, err = explainCommand(ctx, l.client.Database(entry.Database), cmd, VerbosityQueryPlanner)
+			cancel()
+		}
+		if err != nil {
+			entry.ExplainFail = err.Error()
+		} else {
+			entry.PlanStages = planStages(plan)
+			entry.CollScan = containsString(entry.PlanStages, "COLLSCAN")
+		}
+	}
+	ctx, cancel := context.WithTimeout(Ctx(), timeout)
+	defer cancel()
+	if _, err := l.collection().InsertOne(ctx, entry); err != nil {
+		l.logger.Error("recording slow query failed", "error", err)
+	}
+}
+
+func (l *slowQueryLog) entries(ctx context.Context, limit int64) ([]SlowQuery, error) {
+	opts := options.Find().SetSort(bson.M{"$natural": -1}).SetLimit(limit)
+	cursor, err := l.collection().Find(ctx, bson.M{}, opts)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]SlowQuery, 0)
+	err = cursor.All(ctx, &result)
+	return result, err
+}
+
+func (b MongoClient) SlowQueries(limit int64) ([]SlowQuery, error) {
+	if b.slowQueries == nil {
+		return nil, errSlowQueryLogDisabled
+	}
+	return b.slowQueries.entries(b.Context(), limit)
+}
+
+// explainableCommand strips the session and cluster fields the driver adds to a command
+// from a command as captured by the command monitor.
+func explainableCommand(command bson.Raw) (bson.D, error) {
+	elements, err := command.Elements()
+	if err != nil {
+		return nil, err
+	}
+	cmd := bson.D{}
+	for _, e := range elements {
+		key := e.Key()
+		if strings.HasPrefix(key, "$") || key == "lsid" || key == "txnNumber" || key == "writeConcern" || key == "readConcern" {
+			continue
+		}
+		cmd = append(cmd, bson.E{Key: key, Value: e.Value()})
+	}
+	return cmd, nil
+}
+
+func lookup(doc bson.Raw, key string) interface{} {
+	value, err := doc.LookupErr(key)
+	if err != nil {
+		return nil
+	}
+	var result interface{}
+	if err := value.Unmarshal(&result); err != nil {
+		return nil
+	}
+	return result
+}
+
+// redact replaces all values of a filter or pipeline by "?", keeping field names and operators.
+func redact(v interface{}) interface{} {
+	switch value := v.(type) {
+	case nil:
+		return nil
+	case bson.D:
+		result := bson.D{}
+		for _, e := range value {
+			result = append(result, bson.E{Key: e.Key, Value: redact(e.Value)})
+		}
+		return result
+	case bson.M:
+		result := bson.M{}
+		for k, child := range value {
+			result[k] = redact(child)
+		}
+		return result
+	case bson.A:
+		result := bson.A{}
+		for _, child := range value {
+			result = append(result, redact(child))
+		}
+		return result
+	default:
+		return redacted
+	}
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func GetSlowQueries(mongoClient *MongoClient) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		client := mongoClient.WithContext(r.Context())
+		limit := int64(defaultSlowQueryLimit)
+		if v := r.URL.Query().Get("limit"); v != "" {
+			parsed, err := strconv.ParseInt(v, 10, 64)
+			if checkError(err, w, r) {
+				return
+			}
+			limit = parsed
+		}
+		data, err := client.SlowQueries(limit)
+		if checkError(err, w, r) {
+			return
+		}
+		writeResponse(w, r, data)
+	}
+}
@@ -0,0 +1,227 @@
+package mongo
+
+import (
+	"errors"
+	"github.com/gorilla/mux"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	AuditRestore = "restore"
+	AuditPurge   = "purge"
+
+	deletedAtField = "deletedAt"
+	deletedByField = "deletedBy"
+
+	defaultPurgeInterval = time.Hour
+)
+
+var errSoftDeleteDisabled = errors.New("collection does not use soft deletes")
+
+// SoftDeleteConfig enables soft deletes for the collections in Namespaces, given as
+// "database.collection" or "database.*". Deletes set deletedAt and deletedBy instead of
+// removing the document, finds, counts and aggregations exclude deleted documents. With a
+// Retention, deleted documents are purged every PurgeInterval once they are older.
+type SoftDeleteConfig struct {
+	Namespaces    []string
+	Retention     time.Duration
+	PurgeInterval time.Duration
+}
+
+func (b MongoClient) softDeletes(database, collection string) bool {
+	return b.config != nil && b.config.SoftDelete != nil && matchNamespace(b.config.SoftDelete.Namespaces, database, collection)
+}
+
+// visible adds the exclusion of deleted documents to filter, unless the filter selects by
+// deletedAt itself.
+func (b MongoClient) visible(database, collection string, filter bson.M) bson.M {
+	if !b.softDeletes(database, collection) {
+		return filter
+	}
+	if _, ok := filter[deletedAtField]; ok {
+		return filter
+	}
+	result := bson.M{deletedAtField: bson.M{"$exists": false}}
+	for k, v := range filter {
+		result[k] = v
+	}
+	return result
+}
+
+// visiblePipeline prepends the exclusion of deleted documents to an aggregation pipeline.
+func (b MongoClient) visiblePipeline(database, collection string, pipeline interface{}) interface{} {
+	if !b.softDeletes(database, collection) {
+		return pipeline
+	}
+	match := bson.D{{Key: "$match", Value: bson.M{deletedAtField: bson.M{"$exists": false}}}}
+	switch stages := pipeline.(type) {
+	case bson.A:
+		return append(bson.A{match}, stages...)
+	case []interface{}:
+		return append([]interface{}{match}, stages...)
+	case mongo.Pipeline:
+		return append(mongo.Pipeline{match}, stages...)
+	case []bson.D:
+		return append([]bson.D{match}, stages...)
+	case []bson.M:
+		return append([]bson.M{{"$match": match[0].Value}}, stages...)
+	}
+	return pipeline
+}
+
+// softDelete marks the document matching filter as deleted.
+func (b MongoClient) softDelete(col *mongo.Collection, database, collection string, filter bson.M) error {
+	update := bson.M{"$set": bson.M{deletedAtField: time.Now(), deletedByField: PrincipalFromContext(b.Context())}}
+	var before bson.M
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.Before)
+	err := col.FindOneAndUpdate(b.Context(), b.visible(database, collection, filter), update, opts).Decode(&before)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	b.auditRecord(AuditDelete, database, collection, before[documentIDField], before, b.auditAfter(col, before))
+	return nil
+}
+
+// FindTrash returns the cursor of the deleted documents of a collection, the caller has to
+// close it.
+func (b MongoClient) FindTrash(database, collection string, opts ...*options.FindOptions) (*mongo.Cursor, error) {
+	if !b.softDeletes(database, collection) {
+		return nil, errSoftDeleteDisabled
+	}
+	col, err := b.GetCollection(database, collection, b.config.databaseOptions, b.config.collectionOptions)
+	if err != nil {
+		return nil, err
+	}
+	return findMany(b.Context(), col, bson.M{deletedAtField: bson.M{"$exists": true}}, opts...)
+}
+
+// Undelete restores the deleted document matching filter and returns it.
+func (b MongoClient) Undelete(database, collection string, filter bson.M) (bson.M, error) {
+	if !b.softDeletes(database, collection) {
+		return nil, errSoftDeleteDisabled
+	}
+	col, err := b.GetCollection(database, collection, b.config.databaseOptions, b.config.collectionOptions)
+	if err != nil {
+		return nil, err
+	}
+	deleted := bson.M{deletedAtField: bson.M{"$exists": true}}
+	for k, v := range filter {
+		deleted[k] = v
+	}
+	update := bson.M{"$unset": bson.M{deletedAtField: "", deletedByField: ""}}
+	res := col.FindOneAndUpdate(b.Context(), deleted, update, options.FindOneAndUpdate().SetReturnDocument(options.After))
+	var doc bson.M
+	if err := res.Decode(&doc); err != nil {
+		return nil, err
+	}
+	b.auditRecord(AuditRestore, database, collection, doc[documentIDField], nil, doc)
+	return doc, nil
+}
+
+// purger hard deletes soft deleted documents after the retention period.
+type purger struct {
+	client MongoClient
+	config SoftDeleteConfig
+	stop   chan struct{}
+	once   sync.Once
+}
+
+func newPurger(client MongoClient, config SoftDeleteConfig) *purger {
+	if config.PurgeInterval <= 0 {
+		config.PurgeInterval = defaultPurgeInterval
+	}
+	return &purger{client: client, config: config, stop: make(chan struct{})}
+}
+
+func (p *purger) run() {
+	ticker := time.NewTicker(p.config.PurgeInterval)
+	defer ticker.Stop()
+	for {
+		p.purge()
+		select {
+		case <-p.stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (p *purger) close() {
+	p.once.Do(func() { close(p.stop) })
+}
+
+func (p *purger) purge() {
+	cutoff := time.Now().Add(-p.config.Retention)
+	for _, namespace := range p.config.Namespaces {
+		dot := strings.Index(namespace, ".")
+		if dot < 0 {
+			continue
+		}
+		database := namespace[:dot]
+		collections := []string{namespace[dot+1:]}
+		if collections[0] == "*" {
+			names, err := p.client.client.Database(database).ListCollectionNames(Ctx(), bson.M{})
+			if err != nil {
+				p.client.logger().Error("listing collections to purge failed", "database", database, "error", err)
+				continue
+			}
+			collections = names
+		}
+		for _, collection := range collections {
+			if strings.HasPrefix(collection, systemCollectionPrefix) {
+				continue
+			}
+			col := p.client.client.Database(database).Collection(collection)
+			res, err := col.DeleteMany(Ctx(), bson.M{deletedAtField: bson.M{"$lt": cutoff}})
+			if err != nil {
+				p.client.logger().Error("purging deleted documents failed", "database", database, "collection", collection, "error", err)
+				continue
+			}
+			if res.DeletedCount > 0 {
+				p.client.logger().Info("purged deleted documents", "database", database, "collection", collection, "count", res.DeletedCount)
+				p.client.auditRecord(AuditPurge, database, collection, nil, nil, nil)
+			}
+		}
+	}
+}
+
+func GetTrash(mongoClient *MongoClient) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		client := mongoClient.WithContext(r.Context())
+		vars := mux.Vars(r)
+		opts, _, err := findOptions(r.URL.Query())
+		if checkError(err, w, r) {
+			return
+		}
+		cursor, err := client.FindTrash(vars["database"], vars["collection"], opts)
+		if checkError(err, w, r) {
+			return
+		}
+		writeCursor(client.Context(), cursor, w, r, client.afterFind(vars["database"], vars["collection"]))
+	}
+}
+
+func UndeleteDocument(mongoClient *MongoClient) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		client := mongoClient.WithContext(r.Context())
+		vars := mux.Vars(r)
+		filter, err := documentFilter(vars["document"])
+		if checkError(err, w, r) {
+			return
+		}
+		data, err := client.Undelete(vars["database"], vars["collection"], filter)
+		if checkError(err, w, r) {
+			return
+		}
+		writeResponse(w, r, data)
+	}
+}
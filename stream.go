@@ -0,0 +1,84 @@
+package mongo
+
+import (
+	"context"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+const (
+	batchSizeParam    = "batchSize"
+	jsonContentType   = "application/json"
+	ndjsonContentType = "application/x-ndjson"
+)
+
+// FindCursor returns the cursor of a find, the caller has to close it.
+func (b MongoClient) FindCursor(database string, collection string, filter bson.M, opts ...*options.FindOptions) (*mongo.Cursor, error) {
+	col, err := b.GetCollection(database, collection, b.config.databaseOptions, b.config.collectionOptions)
+	if err != nil {
+		return nil, err
+	}
+	return findMany(b.Context(), col, b.visible(database, collection, filter), opts...)
+}
+
+func batchSize(query url.Values) (int32, error) {
+	v := query.Get(batchSizeParam)
+	if v == "" {
+		return 0, nil
+	}
+	size, err := strconv.ParseInt(v, 10, 32)
+	return int32(size), err
+}
+
+// writeCursor streams the documents of cursor in the format requested by the client. Only
+// one batch of the cursor is held in memory, the response is flushed after each batch. An
+// error after the first document has been written aborts the response, so clients can not
+// mistake it for a complete result. A non nil hook is called with every decoded document.
+func writeCursor(ctx context.Context, cursor *mongo.Cursor, w http.ResponseWriter, r *http.Request, hook func(doc bson.M) error) {
+	defer cursor.Close(ctx)
+	f := negotiateFormat(r)
+	w.Header().Set("Content-Type", f.contentType())
+	flusher, _ := w.(http.Flusher)
+	stream := newStreamWriter(f, w, r)
+	abort := func(err error) {
+		logError(r, err)
+		panic(http.ErrAbortHandler)
+	}
+	for cursor.Next(ctx) {
+		var doc interface{} = cursor.Current
+		if hook != nil {
+			decoded := bson.M{}
+			err := cursor.Decode(&decoded)
+			if err == nil {
+				err = hook(decoded)
+			}
+			if err != nil && stream.written == 0 {
+				checkError(err, w, r)
+				return
+			} else if err != nil {
+				abort(err)
+			}
+			doc = decoded
+		}
+		if err := stream.write(doc); err != nil {
+			abort(err)
+		}
+		if flusher != nil && cursor.RemainingBatchLength() == 0 {
+			flusher.Flush()
+		}
+	}
+	if err := cursor.Err(); err != nil {
+		if stream.written == 0 {
+			checkError(err, w, r)
+			return
+		}
+		abort(err)
+	}
+	if err := stream.finish(); err != nil {
+		abort(err)
+	}
+}
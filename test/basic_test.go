@@ -6,11 +6,14 @@ import (
 	"testing"
 )
 
-func getClient() (*mongo.MongoClient, error) {
+func getClient(configure ...func(conf *mongo.MongoConfig)) (*mongo.MongoClient, error) {
 	conf := mongo.DefaultMongoConfig()
 	conf.MongoUri = "mongodb://localhost:27017"
 	conf.MongoUser = "mongoadmin"
 	conf.MongoPassword = "secret"
+	for _, fn := range configure {
+		fn(conf)
+	}
 	return mongo.NewMongoClient(conf)
 }
 func TestPing(t *testing.T) {
@@ -0,0 +1,39 @@
+package test
+
+import (
+	mongo "github.com/z26100/generic-mongo-client"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"testing"
+)
+
+func TestEncryptionSchema(t *testing.T) {
+	key := primitive.Binary{Subtype: 4, Data: make([]byte, 16)}
+	ssn := mongo.EncryptedField(key, "string", true)
+	card := mongo.EncryptedField(key, "", false)
+	schema, err := mongo.EncryptionSchema(map[string]bson.M{"ssn": ssn, "payment.card.number": card, "payment.card.cvc": card})
+	if err != nil {
+		t.Fatal(err)
+	}
+	properties := schema["properties"].(bson.M)
+	if properties["ssn"].(bson.M)["encrypt"].(bson.M)["algorithm"] != mongo.AlgorithmDeterministic {
+		t.Errorf("unexpected ssn field %v", properties["ssn"])
+	}
+	payment := properties["payment"].(bson.M)["properties"].(bson.M)
+	cardProperties := payment["card"].(bson.M)["properties"].(bson.M)
+	if len(cardProperties) != 2 || cardProperties["number"].(bson.M)["encrypt"].(bson.M)["algorithm"] != mongo.AlgorithmRandom {
+		t.Errorf("unexpected card properties %v", cardProperties)
+	}
+
+	invalid := []map[string]bson.M{
+		{"payment": card, "payment.card": card},
+		{"payment.card": card, "payment.card.number": card, "payment-id": card},
+		{"": card},
+		{"payment..card": card},
+	}
+	for _, fields := range invalid {
+		if _, err := mongo.EncryptionSchema(fields); err == nil {
+			t.Errorf("expected an error for %v", fields)
+		}
+	}
+}
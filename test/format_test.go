@@ -0,0 +1,141 @@
+package test
+
+import (
+	"encoding/json"
+	mongo "github.com/z26100/generic-mongo-client"
+	"go.mongodb.org/mongo-driver/bson"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// quotaHandler serves the quota route, a list written like every other response, so the
+// formats and envelopes can be tested without a database.
+func quotaHandler(envelope string) http.Handler {
+	return mongo.NewHandler(&mongo.MongoClient{}, &mongo.HandlerOptions{
+		PathPrefix: "/api",
+		Logger:     mongo.NopLogger(),
+		Envelope:   envelope,
+		RateLimit:  &mongo.RateLimitConfig{Rate: 100, Burst: 100},
+	})
+}
+
+func TestResponseFormat(t *testing.T) {
+	cases := []struct {
+		accept, query string
+		contentType   string
+		contains      []string
+		excludes      []string
+	}{
+		{"", "", "application/json", []string{`[{"key":"ip:192.0.2.1"`, `"lastSeen":{"$date":`, `"allowed":1`}, nil},
+		{"application/json; format=canonical", "", "application/json", []string{`"allowed":{"$numberLong":"1"}`}, nil},
+		{"", "format=plain", "application/json", []string{`"allowed":1`, `"lastSeen":"`}, []string{"$date"}},
+		{"text/html, application/json; format=plain", "", "application/json", []string{`"lastSeen":"`}, []string{"$date"}},
+		{"application/x-ndjson", "", "application/x-ndjson", []string{`{"key":"ip:192.0.2.1"`}, []string{"["}},
+		{"", "format=ndjson", "application/x-ndjson", []string{`{"key":"ip:192.0.2.1"`}, []string{"["}},
+		{"text/csv", "", "text/csv", []string{"key,tokens,allowed,rejected,lastSeen\nip:192.0.2.1,"}, nil},
+		{"application/json", "format=csv&fields=key,allowed", "text/csv", []string{"key,allowed\nip:192.0.2.1,1\n"}, nil},
+		{"application/bson", "", "application/bson", nil, nil},
+	}
+	for _, c := range cases {
+		r := httptest.NewRequest("GET", "/api/_admin/quotas?"+c.query, nil)
+		if c.accept != "" {
+			r.Header.Set("Accept", c.accept)
+		}
+		w := httptest.NewRecorder()
+		quotaHandler(mongo.EnvelopeNone).ServeHTTP(w, r)
+		if contentType := w.Header().Get("Content-Type"); !strings.HasPrefix(contentType, c.contentType) {
+			t.Errorf("%q %q: expected %s, got %s", c.accept, c.query, c.contentType, contentType)
+		}
+		body := w.Body.String()
+		for _, s := range c.contains {
+			if !strings.Contains(body, s) {
+				t.Errorf("%q %q: expected %s in %s", c.accept, c.query, s, body)
+			}
+		}
+		for _, s := range c.excludes {
+			if strings.Contains(body, s) {
+				t.Errorf("%q %q: unexpected %s in %s", c.accept, c.query, s, body)
+			}
+		}
+		if c.contentType == "application/bson" {
+			var quota mongo.Quota
+			if err := bson.Unmarshal(w.Body.Bytes(), &quota); err != nil || quota.Key != "ip:192.0.2.1" {
+				t.Errorf("unexpected BSON quota %+v: %v", quota, err)
+			}
+		}
+	}
+}
+
+func TestResponseEnvelope(t *testing.T) {
+	cases := []struct {
+		envelope, query string
+		prefix          string
+		meta            map[string]interface{}
+	}{
+		{"", "", `{"body":[`, nil},
+		{mongo.EnvelopeNone, "", `[`, nil},
+		{mongo.EnvelopeMetadata, "", `{"data":[`, map[string]interface{}{"count": 1.0, "total": 1.0, "next": nil, "prev": nil}},
+		{mongo.EnvelopeMetadata, "limit=1&skip=1", `{"data":[`, map[string]interface{}{
+			"count": 1.0, "total": nil, "next": "/api/_admin/quotas?limit=1&skip=2", "prev": "/api/_admin/quotas?limit=1"}},
+		{mongo.EnvelopeMetadata, "limit=2&skip=5", `{"data":[`, map[string]interface{}{
+			"count": 1.0, "total": nil, "next": nil, "prev": "/api/_admin/quotas?limit=2&skip=3"}},
+		{mongo.EnvelopeMetadata, "skip=1", `{"data":[`, map[string]interface{}{"next": nil, "prev": "/api/_admin/quotas"}},
+	}
+	for _, c := range cases {
+		w := httptest.NewRecorder()
+		quotaHandler(c.envelope).ServeHTTP(w, httptest.NewRequest("GET", "/api/_admin/quotas?"+c.query, nil))
+		if !strings.HasPrefix(w.Body.String(), c.prefix) {
+			t.Errorf("%s %q: expected prefix %s, got %s", c.envelope, c.query, c.prefix, w.Body.String())
+			continue
+		}
+		if c.meta == nil {
+			continue
+		}
+		var doc map[string]interface{}
+		if err := json.Unmarshal(w.Body.Bytes(), &doc); err != nil {
+			t.Fatal(err)
+		}
+		for key, expected := range c.meta {
+			if doc[key] != expected {
+				t.Errorf("%s %q: expected %s %v, got %v", c.envelope, c.query, key, expected, doc[key])
+			}
+		}
+	}
+}
+
+func TestHealthFormat(t *testing.T) {
+	cases := []struct {
+		accept, contentType, body string
+	}{
+		{"", "application/json", `{"status":"up"}`},
+		{"text/csv", "text/csv", "status\nup\n"},
+	}
+	for _, c := range cases {
+		r := httptest.NewRequest("GET", "/api/healthz", nil)
+		r.Header.Set("Accept", c.accept)
+		w := httptest.NewRecorder()
+		quotaHandler(mongo.EnvelopeNone).ServeHTTP(w, r)
+		if contentType := w.Header().Get("Content-Type"); !strings.HasPrefix(contentType, c.contentType) || w.Body.String() != c.body {
+			t.Errorf("%q: expected %s %q, got %s %q", c.accept, c.contentType, c.body, contentType, w.Body.String())
+		}
+	}
+}
+
+func TestHealthEnvelope(t *testing.T) {
+	cases := []struct {
+		envelope, prefix string
+	}{
+		{"", `{"body":{"status":"up"}}`},
+		{mongo.EnvelopeNone, `{"status":"up"}`},
+		{mongo.EnvelopeMetadata, `{"data":{"status":"up"},`},
+	}
+	for _, c := range cases {
+		w := httptest.NewRecorder()
+		quotaHandler(c.envelope).ServeHTTP(w, httptest.NewRequest("GET", "/api/healthz", nil))
+		if !strings.HasPrefix(w.Body.String(), c.prefix) {
+			t.Errorf("%s: expected prefix %s, got %s", c.envelope, c.prefix, w.Body.String())
+		}
+	}
+}
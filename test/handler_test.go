@@ -0,0 +1,107 @@
+package test
+
+import (
+	mongo "github.com/z26100/generic-mongo-client"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestNewHandler(t *testing.T) {
+	var order []string
+	middleware := func(name string) mongo.Middleware {
+		return func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				order = append(order, name)
+				next.ServeHTTP(w, r)
+			})
+		}
+	}
+	handler := mongo.NewHandler(&mongo.MongoClient{}, &mongo.HandlerOptions{
+		PathPrefix: "/api",
+		ReadOnly:   true,
+		Routes: []mongo.Route{{Path: "/version", Methods: "GET", HandlerFc: func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusTeapot)
+		}}},
+		Middleware: []mongo.Middleware{middleware("outer"), middleware("inner")},
+	})
+
+	cases := []struct {
+		method, path, body string
+		status             int
+	}{
+		{"GET", "/api/version", "", http.StatusTeapot},
+		{"DELETE", "/api/shop/orders", "", http.StatusMethodNotAllowed},
+		{"PUT", "/api/shop/orders/1", "", http.StatusMethodNotAllowed},
+		{"GET", "/api/shop/system.users", "", http.StatusForbidden},
+		{"POST", "/api/shop/orders/_aggregate", `[{"$match": {}}, {"$out": "copy"}]`, http.StatusForbidden},
+		{"POST", "/api/shop/orders/_aggregate", `{"pipeline": [{"$merge": {"into": "copy"}}]}`, http.StatusForbidden},
+	}
+	for _, c := range cases {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(c.method, c.path, strings.NewReader(c.body)))
+		if w.Code != c.status {
+			t.Errorf("%s %s: expected %d, got %d", c.method, c.path, c.status, w.Code)
+		}
+	}
+	if len(order) < 2 || order[0] != "outer" || order[1] != "inner" {
+		t.Errorf("unexpected middleware order %v", order)
+	}
+}
+
+func TestSafeMode(t *testing.T) {
+	handler := mongo.NewHandler(&mongo.MongoClient{}, &mongo.HandlerOptions{
+		Logger:   mongo.NopLogger(),
+		SafeMode: &mongo.SafeModeConfig{Databases: "test_.*"},
+	})
+	cases := []struct {
+		path, confirm string
+		status        int
+	}{
+		{"/prod", "prod", http.StatusForbidden},
+		{"/test_shop/orders", "", http.StatusPreconditionRequired},
+		{"/test_shop/orders", "test_shop", http.StatusPreconditionRequired},
+	}
+	for _, c := range cases {
+		r := httptest.NewRequest("DELETE", c.path, nil)
+		r.Header.Set("X-Confirm-Drop", c.confirm)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		if w.Code != c.status {
+			t.Errorf("DELETE %s: expected %d, got %d", c.path, c.status, w.Code)
+		}
+	}
+}
+
+// endlessDocument is a bulk body whose first document never ends.
+type endlessDocument struct{ started bool }
+
+func (e *endlessDocument) Read(p []byte) (int, error) {
+	if !e.started {
+		e.started = true
+		return copy(p, `[{"a": "`), nil
+	}
+	for i := range p {
+		p[i] = 'x'
+	}
+	return len(p), nil
+}
+
+func TestBulkInsertTooLarge(t *testing.T) {
+	handler := mongo.NewHandler(&mongo.MongoClient{}, &mongo.HandlerOptions{Logger: mongo.NopLogger()})
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("POST", "/shop/orders/_bulk", &endlessDocument{}))
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("expected %d, got %d", http.StatusRequestEntityTooLarge, w.Code)
+	}
+}
+
+func TestMaxBodySize(t *testing.T) {
+	handler := mongo.NewHandler(&mongo.MongoClient{}, &mongo.HandlerOptions{Logger: mongo.NopLogger(), MaxBodySize: 16})
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("POST", "/shop/orders", strings.NewReader(`{"note": "longer than the limit"}`)))
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("expected %d, got %d", http.StatusRequestEntityTooLarge, w.Code)
+	}
+}
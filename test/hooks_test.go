@@ -0,0 +1,93 @@
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	mongo "github.com/z26100/generic-mongo-client"
+	"go.mongodb.org/mongo-driver/bson"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestVeto(t *testing.T) {
+	err := fmt.Errorf("insert: %w", mongo.Veto("price must be positive"))
+	var hookErr *mongo.HookError
+	if !errors.As(err, &hookErr) {
+		t.Fatalf("expected a HookError, got %v", err)
+	}
+	if hookErr.Status != http.StatusUnprocessableEntity || hookErr.Message != "price must be positive" {
+		t.Errorf("unexpected veto %+v", hookErr)
+	}
+}
+
+func TestHooks(t *testing.T) {
+	client, err := getClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	if err := client.DropCollection("hooktest", "items"); err != nil {
+		t.Fatal(err)
+	}
+	var calls []string
+	client.RegisterHooks("hooktest", "items", mongo.Hooks{
+		BeforeInsert: func(ctx context.Context, doc bson.M) error {
+			if doc["veto"] == true {
+				return &mongo.HookError{Status: http.StatusConflict, Message: "vetoed"}
+			}
+			doc["hooks"] = append(doc["hooks"].(bson.A), "collection")
+			return nil
+		},
+		AfterInsert: func(ctx context.Context, doc bson.M) {
+			calls = append(calls, "afterInsert")
+		},
+		BeforeUpdate: func(ctx context.Context, filter bson.M, update bson.M) error {
+			calls = append(calls, "beforeUpdate")
+			return nil
+		},
+	})
+	client.RegisterHooks("hooktest", "*", mongo.Hooks{
+		BeforeInsert: func(ctx context.Context, doc bson.M) error {
+			doc["hooks"] = bson.A{"database"}
+			return nil
+		},
+	})
+	handler := mongo.NewHandler(client, &mongo.HandlerOptions{Logger: mongo.NopLogger()})
+
+	cases := []struct {
+		method, path, body string
+		status             int
+		hooks              string
+		calls              []string
+	}{
+		{"POST", "/hooktest/items", `{"price": 1}`, http.StatusOK, "[database collection]", []string{"afterInsert"}},
+		{"POST", "/hooktest/items", `{"veto": true}`, http.StatusConflict, "", nil},
+		{"PUT", "/hooktest/items/upserted", `{"price": 2}`, http.StatusOK, "[database collection]", []string{"afterInsert"}},
+		{"PUT", "/hooktest/items/upserted", `{"price": 3}`, http.StatusOK, "", []string{"beforeUpdate"}},
+	}
+	for _, c := range cases {
+		calls = nil
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(c.method, c.path, strings.NewReader(c.body)))
+		if w.Code != c.status {
+			t.Errorf("%s %s: expected %d, got %d", c.method, c.path, c.status, w.Code)
+			continue
+		}
+		if c.hooks != "" {
+			var doc map[string]interface{}
+			if err := json.Unmarshal(w.Body.Bytes(), &doc); err != nil {
+				t.Fatal(err)
+			}
+			if hooks := fmt.Sprint(doc["hooks"]); hooks != c.hooks {
+				t.Errorf("%s %s: expected hooks %s, got %s", c.method, c.path, c.hooks, hooks)
+			}
+		}
+		if fmt.Sprint(calls) != fmt.Sprint(c.calls) {
+			t.Errorf("%s %s: expected calls %v, got %v", c.method, c.path, c.calls, calls)
+		}
+	}
+}
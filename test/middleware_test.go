@@ -0,0 +1,82 @@
+package test
+
+import (
+	"compress/gzip"
+	mongo "github.com/z26100/generic-mongo-client"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRecover(t *testing.T) {
+	handler := mongo.Chain(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	}), mongo.Recover(mongo.NopLogger()))
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	if w.Code != http.StatusInternalServerError {
+		t.Fatalf("expected 500, got %d", w.Code)
+	}
+
+	abort := mongo.Recover(mongo.NopLogger())(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic(http.ErrAbortHandler)
+	}))
+	defer func() {
+		if v := recover(); v != http.ErrAbortHandler {
+			t.Fatalf("expected http.ErrAbortHandler to be passed on, got %v", v)
+		}
+	}()
+	abort.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+}
+
+func TestGzip(t *testing.T) {
+	handler := mongo.Gzip(gzip.DefaultCompression)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"body":[]}`))
+	}))
+	r := httptest.NewRequest("GET", "/", nil)
+	r.Header.Set("Accept-Encoding", "gzip")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	if w.Header().Get("Content-Encoding") != "gzip" {
+		t.Fatal("expected gzip encoding")
+	}
+	reader, err := gzip.NewReader(w.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, err := ioutil.ReadAll(reader)
+	if err != nil || string(body) != `{"body":[]}` {
+		t.Fatalf("unexpected body %q, %v", body, err)
+	}
+}
+
+type captureLogger struct {
+	warnings [][]interface{}
+}
+
+func (l *captureLogger) Debug(msg string, keysAndValues ...interface{}) {}
+func (l *captureLogger) Info(msg string, keysAndValues ...interface{})  {}
+func (l *captureLogger) Error(msg string, keysAndValues ...interface{}) {}
+func (l *captureLogger) Warn(msg string, keysAndValues ...interface{}) {
+	l.warnings = append(l.warnings, keysAndValues)
+}
+
+func TestAccessLogBehindGzip(t *testing.T) {
+	logger := &captureLogger{}
+	handler := mongo.NewHandler(&mongo.MongoClient{}, &mongo.HandlerOptions{
+		Logger:     logger,
+		AccessLog:  true,
+		Middleware: []mongo.Middleware{mongo.Gzip(gzip.DefaultCompression)},
+	})
+	r := httptest.NewRequest("GET", "/shop/system.users", nil)
+	r.Header.Set("Accept-Encoding", "gzip")
+	handler.ServeHTTP(httptest.NewRecorder(), r)
+	if len(logger.warnings) != 1 {
+		t.Fatalf("expected one access log entry, got %v", logger.warnings)
+	}
+	fields := logger.warnings[0]
+	if len(fields) < 2 || fields[len(fields)-2] != "error" {
+		t.Errorf("expected the error in the access log entry, got %v", fields)
+	}
+}
@@ -0,0 +1,70 @@
+package test
+
+import (
+	mongo "github.com/z26100/generic-mongo-client"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestValidateNamespace(t *testing.T) {
+	valid := [][2]string{
+		{"shop", ""},
+		{"shop", "user_events"},
+		{"shop", "orders2024"},
+		{"shop", "camelCase"},
+		{"shop", "logs.archive"},
+		{"my-db", "a b"},
+	}
+	for _, ns := range valid {
+		if err := mongo.ValidateNamespace(ns[0], ns[1]); err != nil {
+			t.Errorf("%s.%s should be valid: %v", ns[0], ns[1], err)
+		}
+	}
+	invalid := [][2]string{
+		{"", "orders"},
+		{"shop.eu", "orders"},
+		{"shop", "price$"},
+		{"shop", "system.users"},
+		{strings.Repeat("a", 64), ""},
+	}
+	for _, ns := range invalid {
+		if err := mongo.ValidateNamespace(ns[0], ns[1]); err == nil {
+			t.Errorf("%s.%s should be invalid", ns[0], ns[1])
+		}
+	}
+}
+
+func TestInternalNamespaces(t *testing.T) {
+	client, err := getClient(func(conf *mongo.MongoConfig) {
+		conf.Audit = &mongo.AuditConfig{}
+		conf.SlowQuery = &mongo.SlowQueryConfig{}
+		conf.History = &mongo.HistoryConfig{Namespaces: []string{"shop.orders"}}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	handler := mongo.NewHandler(client, &mongo.HandlerOptions{Logger: mongo.NopLogger()})
+
+	cases := []struct {
+		path     string
+		reserved bool
+	}{
+		{"/admin/audit", true},
+		{"/admin/slowqueries", true},
+		{"/shop/orders_history", true},
+		{"/shop/customers_history", false},
+		// webhooks are not enabled, their collections are ordinary ones
+		{"/admin/webhooks", false},
+		{"/admin/webhooks_deliveries", false},
+	}
+	for _, c := range cases {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest("GET", c.path, nil))
+		if reserved := w.Code == http.StatusForbidden; reserved != c.reserved {
+			t.Errorf("GET %s: expected reserved %v, got status %d", c.path, c.reserved, w.Code)
+		}
+	}
+}
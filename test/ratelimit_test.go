@@ -0,0 +1,119 @@
+package test
+
+import (
+	"fmt"
+	mongo "github.com/z26100/generic-mongo-client"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRateLimiterTake(t *testing.T) {
+	limiter := mongo.NewRateLimiter(mongo.RateLimitConfig{Rate: 1, Burst: 3}, nil)
+	for i := 0; i < 3; i++ {
+		if ok, _ := limiter.Take("a", 1); !ok {
+			t.Fatalf("request %d should be allowed", i)
+		}
+	}
+	ok, retryAfter := limiter.Take("a", 1)
+	if ok || retryAfter <= 0 {
+		t.Fatalf("expected rejection with retry after, got %v %v", ok, retryAfter)
+	}
+	if ok, _ := limiter.Take("b", 3); !ok {
+		t.Fatal("buckets must be independent per key")
+	}
+}
+
+func TestRateLimiterHandler(t *testing.T) {
+	limiter := mongo.NewRateLimiter(mongo.RateLimitConfig{Rate: 0.1, Burst: 10, KeyBy: mongo.RateLimitByIP}, nil)
+	route := mongo.Route{Path: "/{database}/{collection}", Cost: 10}
+	handler := limiter.Handler(route, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/db/col", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/db/col", nil))
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected 429, got %d", w.Code)
+	}
+	if w.Header().Get("Retry-After") == "" {
+		t.Fatal("expected Retry-After header")
+	}
+	if quotas := limiter.Quotas(); len(quotas) != 1 || quotas[0].Rejected != 1 {
+		t.Fatalf("unexpected quotas %v", quotas)
+	}
+}
+
+func TestRateLimiterIgnoresForwardedFor(t *testing.T) {
+	limiter := mongo.NewRateLimiter(mongo.RateLimitConfig{Rate: 0.1, Burst: 1, KeyBy: mongo.RateLimitByIP}, nil)
+	handler := limiter.Handler(mongo.Route{Path: "/"}, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	allowed := 0
+	for i := 0; i < 10; i++ {
+		r := httptest.NewRequest("GET", "/", nil)
+		r.Header.Set("X-Forwarded-For", fmt.Sprintf("10.0.0.%d", i))
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		if w.Code == http.StatusOK {
+			allowed++
+		}
+	}
+	if allowed != 1 {
+		t.Fatalf("rotating X-Forwarded-For of an untrusted caller must not bypass the limit, %d allowed", allowed)
+	}
+}
+
+func TestRateLimiterIgnoresUnverifiedPrincipal(t *testing.T) {
+	limiter := mongo.NewRateLimiter(mongo.RateLimitConfig{Rate: 0.1, Burst: 1, KeyBy: mongo.RateLimitByPrincipal}, nil)
+	handler := limiter.Handler(mongo.Route{Path: "/"}, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	allowed := 0
+	for i := 0; i < 10; i++ {
+		r := httptest.NewRequest("GET", "/", nil)
+		r.SetBasicAuth(fmt.Sprintf("user-%d", i), "")
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		if w.Code == http.StatusOK {
+			allowed++
+		}
+	}
+	if allowed != 1 {
+		t.Fatalf("rotating basic auth users without a PrincipalFunc must not bypass the limit, %d allowed", allowed)
+	}
+}
+
+func TestRateLimiterBucketCap(t *testing.T) {
+	limiter := mongo.NewRateLimiter(mongo.RateLimitConfig{Rate: 0, Burst: 1}, nil)
+	for i := 0; i < 10000; i++ {
+		limiter.Take(fmt.Sprintf("key-%d", i), 1)
+	}
+	// key-0 is used again, so key-1 is the least recently used bucket
+	limiter.Take("key-0", 1)
+	limiter.Take("key-10000", 1)
+	quotas := limiter.Quotas()
+	if len(quotas) != 10000 {
+		t.Fatalf("expected 10000 buckets, got %d", len(quotas))
+	}
+	keys := map[string]bool{}
+	for _, quota := range quotas {
+		keys[quota.Key] = true
+	}
+	if !keys["key-0"] || keys["key-1"] || !keys["key-10000"] {
+		t.Fatal("expected the least recently used bucket to be evicted")
+	}
+}
+
+func TestRateLimiterSkipsHealth(t *testing.T) {
+	handler := mongo.NewHandler(&mongo.MongoClient{}, &mongo.HandlerOptions{
+		Logger:    mongo.NopLogger(),
+		RateLimit: &mongo.RateLimitConfig{Rate: 0.1, Burst: 1},
+	})
+	for i := 0; i < 3; i++ {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest("GET", "/healthz", nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("health probe %d: expected 200, got %d", i, w.Code)
+		}
+	}
+}
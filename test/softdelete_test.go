@@ -0,0 +1,50 @@
+package test
+
+import (
+	mongo "github.com/z26100/generic-mongo-client"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestSoftDelete(t *testing.T) {
+	client, err := getClient(func(conf *mongo.MongoConfig) {
+		conf.SoftDelete = &mongo.SoftDeleteConfig{Namespaces: []string{"softtest.items"}}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	for _, collection := range []string{"items", "plain"} {
+		if err := client.DropCollection("softtest", collection); err != nil {
+			t.Fatal(err)
+		}
+	}
+	handler := mongo.NewHandler(client, &mongo.HandlerOptions{Logger: mongo.NopLogger()})
+
+	cases := []struct {
+		method, path, body string
+		status             int
+	}{
+		{"PUT", "/softtest/items/doc1", `{"a": 1}`, http.StatusOK},
+		{"DELETE", "/softtest/items/doc1", "", http.StatusNoContent},
+		{"GET", "/softtest/items/doc1", "", http.StatusNotFound},
+		// a replace must not bring the deleted document back
+		{"PUT", "/softtest/items/doc1", `{"a": 2}`, http.StatusConflict},
+		{"GET", "/softtest/items/doc1", "", http.StatusNotFound},
+		{"GET", "/softtest/items/_trash", "", http.StatusOK},
+		{"POST", "/softtest/items/doc1/_restore", "", http.StatusOK},
+		{"GET", "/softtest/items/doc1", "", http.StatusOK},
+		{"PUT", "/softtest/plain/doc1", `{"a": 1}`, http.StatusOK},
+		{"GET", "/softtest/plain/_trash", "", http.StatusNotFound},
+		{"POST", "/softtest/plain/doc1/_restore", "", http.StatusNotFound},
+	}
+	for _, c := range cases {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(c.method, c.path, strings.NewReader(c.body)))
+		if w.Code != c.status {
+			t.Errorf("%s %s: expected %d, got %d", c.method, c.path, c.status, w.Code)
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package test
+
+import (
+	"context"
+	mongo "github.com/z26100/generic-mongo-client"
+	"go.mongodb.org/mongo-driver/bson"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func waitForDeliveries(t *testing.T, webhooks *mongo.Webhooks, dead bool, count int) []mongo.WebhookDelivery {
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		deliveries, err := webhooks.Deliveries(context.Background(), bson.M{}, dead, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(deliveries) >= count || time.Now().After(deadline) {
+			return deliveries
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestWebhookDelivery(t *testing.T) {
+	var calls int32
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		signature := mongo.SignWebhook("s3cret", r.Header.Get(mongo.WebhookTimestampHeader), body)
+		if r.Header.Get(mongo.WebhookSignatureHeader) != signature {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		// the first attempt fails and is retried
+		if atomic.AddInt32(&calls, 1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer receiver.Close()
+
+	store := mongo.NewMemoryWebhookStore(
+		mongo.WebhookSubscription{ID: "orders", Namespace: "shop.orders", Operations: []string{mongo.AuditInsert},
+			Filter: bson.M{"total": bson.M{"$gte": 100}}, URL: receiver.URL, Secret: "s3cret"},
+		mongo.WebhookSubscription{ID: "users", Namespace: "shop.users", URL: receiver.URL},
+	)
+	webhooks, err := mongo.NewWebhooks(mongo.WebhookConfig{Store: store, Backoff: time.Millisecond}, mongo.NopLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer webhooks.Close()
+
+	webhooks.Publish(mongo.WebhookEvent{Operation: mongo.AuditInsert, Database: "shop", Collection: "orders", Document: bson.M{"total": 20}})
+	webhooks.Publish(mongo.WebhookEvent{Operation: mongo.AuditDelete, Database: "shop", Collection: "orders", Document: bson.M{"total": 200}})
+	webhooks.Publish(mongo.WebhookEvent{Operation: mongo.AuditInsert, Database: "shop", Collection: "orders", Document: bson.M{"total": 200}})
+
+	deliveries := waitForDeliveries(t, webhooks, false, 2)
+	if len(deliveries) != 2 {
+		t.Fatalf("expected 2 delivery attempts, got %d", len(deliveries))
+	}
+	if latest := deliveries[0]; latest.Attempt != 2 || latest.Status != http.StatusNoContent || latest.Subscription != "orders" {
+		t.Errorf("unexpected delivery %+v", latest)
+	}
+	if failed := deliveries[1]; failed.Attempt != 1 || failed.Status != http.StatusServiceUnavailable || failed.Error == "" {
+		t.Errorf("unexpected delivery %+v", failed)
+	}
+}
+
+func TestWebhookDeadLetter(t *testing.T) {
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer receiver.Close()
+
+	store := mongo.NewMemoryWebhookStore(mongo.WebhookSubscription{ID: "all", Namespace: "shop.*", URL: receiver.URL})
+	webhooks, err := mongo.NewWebhooks(mongo.WebhookConfig{Store: store, MaxAttempts: 3, Backoff: time.Millisecond}, mongo.NopLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer webhooks.Close()
+
+	webhooks.Publish(mongo.WebhookEvent{Operation: mongo.AuditUpdate, Database: "shop", Collection: "users"})
+	dead := waitForDeliveries(t, webhooks, true, 1)
+	if len(dead) != 1 || dead[0].Attempt != 3 {
+		t.Fatalf("expected one dead letter after 3 attempts, got %+v", dead)
+	}
+	if deliveries := waitForDeliveries(t, webhooks, false, 3); len(deliveries) != 3 {
+		t.Errorf("expected 3 logged attempts, got %d", len(deliveries))
+	}
+}
+
+func TestWebhookRetryCap(t *testing.T) {
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer receiver.Close()
+
+	store := mongo.NewMemoryWebhookStore(mongo.WebhookSubscription{ID: "all", Namespace: "shop.*", URL: receiver.URL})
+	webhooks, err := mongo.NewWebhooks(mongo.WebhookConfig{Store: store, QueueSize: 1, Backoff: time.Hour}, mongo.NopLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer webhooks.Close()
+
+	// the first delivery waits for its retry, the second finds no room and is dead lettered
+	for i := 1; i <= 2; i++ {
+		webhooks.Publish(mongo.WebhookEvent{Operation: mongo.AuditUpdate, Database: "shop", Collection: "users"})
+		waitForDeliveries(t, webhooks, false, i)
+	}
+	dead := waitForDeliveries(t, webhooks, true, 1)
+	if len(dead) != 1 || dead[0].Attempt != 1 {
+		t.Fatalf("expected one dead letter after the first attempt, got %+v", dead)
+	}
+}
@@ -0,0 +1,127 @@
+package mongo
+
+import (
+	"context"
+	"fmt"
+	"go.mongodb.org/mongo-driver/event"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+	"net/http"
+	"sync"
+)
+
+const (
+	TracingExporterOTLP   = "otlp"
+	TracingExporterStdout = "stdout"
+
+	tracerName         = "github.com/z26100/generic-mongo-client"
+	defaultServiceName = "generic-mongo-client"
+)
+
+// TracingConfig selects the span exporter. Endpoint is the host:port of an OTLP/HTTP
+// collector and only used by the otlp exporter.
+type TracingConfig struct {
+	Exporter    string
+	Endpoint    string
+	Insecure    bool
+	ServiceName string
+}
+
+// SetupTracing installs a global tracer provider and the W3C trace context propagator. The
+// returned function flushes and stops the exporter.
+func SetupTracing(config TracingConfig) (func(context.Context) error, error) {
+	var exporter sdktrace.SpanExporter
+	var err error
+	switch config.Exporter {
+	case TracingExporterStdout:
+		exporter, err = stdouttrace.New(stdouttrace.WithPrettyPrint())
+	case TracingExporterOTLP, "":
+		opts := []otlptracehttp.Option{}
+		if config.Endpoint != "" {
+			opts = append(opts, otlptracehttp.WithEndpoint(config.Endpoint))
+		}
+		if config.Insecure {
+			opts = append(opts, otlptracehttp.WithInsecure())
+		}
+		exporter, err = otlptracehttp.New(Ctx(), opts...)
+	default:
+		err = fmt.Errorf("unknown tracing exporter %s", config.Exporter)
+	}
+	if err != nil {
+		return nil, err
+	}
+	serviceName := config.ServiceName
+	if serviceName == "" {
+		serviceName = defaultServiceName
+	}
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewSchemaless(attribute.String("service.name", serviceName))),
+	)
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	return provider.Shutdown, nil
+}
+
+func traceRoute(route string, next http.Handler) http.Handler {
+	tracer := otel.Tracer(tracerName)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+		ctx, span := tracer.Start(ctx, r.Method+" "+route,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				attribute.String("http.method", r.Method),
+				attribute.String("http.route", route),
+				attribute.String("http.target", r.URL.Path),
+			))
+		defer span.End()
+		recorder := &statusRecorder{ResponseWriter: w}
+		next.ServeHTTP(recorder, r.WithContext(ctx))
+		span.SetAttributes(attribute.Int("http.status_code", recorder.Status()))
+		if recorder.Status() >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(recorder.Status()))
+		}
+	})
+}
+
+// newTracingCommandMonitor starts a client span per driver command as a child of the span
+// in the operation context, see MongoClient.WithContext.
+func newTracingCommandMonitor() *event.CommandMonitor {
+	tracer := otel.Tracer(tracerName)
+	var spans sync.Map
+	return &event.CommandMonitor{
+		Started: func(ctx context.Context, e *event.CommandStartedEvent) {
+			info := newCommandInfo(e)
+			_, span := tracer.Start(ctx, "mongodb."+info.command,
+				trace.WithSpanKind(trace.SpanKindClient),
+				trace.WithAttributes(
+					attribute.String("db.system", "mongodb"),
+					attribute.String("db.name", info.database),
+					attribute.String("db.mongodb.collection", info.collection),
+					attribute.String("db.operation", info.command),
+				))
+			spans.Store(e.RequestID, span)
+		},
+		Succeeded: func(ctx context.Context, e *event.CommandSucceededEvent) {
+			if v, ok := spans.Load(e.RequestID); ok {
+				spans.Delete(e.RequestID)
+				v.(trace.Span).End()
+			}
+		},
+		Failed: func(ctx context.Context, e *event.CommandFailedEvent) {
+			if v, ok := spans.Load(e.RequestID); ok {
+				spans.Delete(e.RequestID)
+				span := v.(trace.Span)
+				span.SetStatus(codes.Error, e.Failure)
+				span.End()
+			}
+		},
+	}
+}
@@ -0,0 +1,732 @@
+package mongo
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	WebhookIDHeader        = "X-Webhook-Id"
+	WebhookEventHeader     = "X-Webhook-Event"
+	WebhookTimestampHeader = "X-Webhook-Timestamp"
+	WebhookSignatureHeader = "X-Webhook-Signature"
+
+	defaultWebhookDatabase        = "admin"
+	defaultWebhookCollection      = "webhooks"
+	webhookDeliveriesSuffix       = "_deliveries"
+	webhookDeadLettersSuffix      = "_deadletters"
+	defaultWebhookMaxAttempts     = 5
+	defaultWebhookBackoff         = time.Second
+	defaultWebhookMaxBackoff      = 5 * time.Minute
+	defaultWebhookTimeout         = 10 * time.Second
+	defaultWebhookWorkers         = 4
+	defaultWebhookQueueSize       = 1000
+	defaultWebhookRefreshInterval = 30 * time.Second
+)
+
+var (
+	errWebhookStoreMissing = errors.New("webhooks need a store")
+	errWebhooksDisabled    = errors.New("webhooks are not enabled")
+	errWebhookQueueFull    = errors.New("webhook queue is full")
+	errWebhookRetriesFull  = errors.New("too many webhook deliveries wait for a retry")
+	errWebhooksClosed      = errors.New("webhooks closed before the delivery succeeded")
+)
+
+// WebhookConfig enables outbound webhooks. Subscriptions are read from Database.Collection
+// unless a Store is set, deliveries are logged to the collection with the suffix
+// _deliveries and failed ones are kept in the one with the suffix _deadletters. Events are
+// published by the write operations of the MongoClient or, with ChangeStreams, from a change
+// stream of the deployment which also covers writes of other clients but needs a replica set.
+// A delivery is attempted MaxAttempts times, the Backoff doubles after every attempt up to
+// MaxBackoff. Events for which the queue of QueueSize deliveries has no room are dropped and
+// logged, failed deliveries beyond QueueSize waiting for a retry are dead lettered.
+type WebhookConfig struct {
+	Store           WebhookStore
+	Database        string
+	Collection      string
+	ChangeStreams   bool
+	MaxAttempts     int
+	Backoff         time.Duration
+	MaxBackoff      time.Duration
+	Timeout         time.Duration
+	Workers         int
+	QueueSize       int
+	RefreshInterval time.Duration
+	Client          *http.Client
+}
+
+// WebhookSubscription posts the events of Namespace, given as "database.collection" or
+// "database.*", to URL. Empty Operations match all operations, the Filter is matched
+// against the changed document, or the deleted one for deletes. Change streams only carry
+// the key of deleted documents, _id and the shard key, so with ChangeStreams the filters of
+// deletes can only match those fields. With a Secret the requests are signed, see SignWebhook.
+type WebhookSubscription struct {
+	ID         interface{} `bson:"_id"`
+	Namespace  string      `bson:"namespace"`
+	Operations []string    `bson:"operations,omitempty"`
+	Filter     bson.M      `bson:"filter,omitempty"`
+	URL        string      `bson:"url"`
+	Secret     string      `bson:"secret,omitempty"`
+	Disabled   bool        `bson:"disabled,omitempty"`
+}
+
+type WebhookEvent struct {
+	ID         string      `bson:"id"`
+	Timestamp  time.Time   `bson:"ts"`
+	Operation  string      `bson:"op"`
+	Database   string      `bson:"database"`
+	Collection string      `bson:"collection,omitempty"`
+	DocumentID interface{} `bson:"documentId,omitempty"`
+	Document   bson.M      `bson:"document,omitempty"`
+	Principal  string      `bson:"principal,omitempty"`
+	RequestID  string      `bson:"requestId,omitempty"`
+}
+
+// WebhookDelivery is the log record of one delivery attempt. Status is the HTTP status
+// answered by the receiver, Error is set for failed attempts.
+type WebhookDelivery struct {
+	Subscription interface{}  `bson:"subscription"`
+	URL          string       `bson:"url"`
+	Event        WebhookEvent `bson:"event"`
+	Attempt      int          `bson:"attempt"`
+	Status       int          `bson:"status,omitempty"`
+	Error        string       `bson:"error,omitempty"`
+	Timestamp    time.Time    `bson:"ts"`
+}
+
+// WebhookStore holds the subscriptions, the delivery log and the dead letters.
+type WebhookStore interface {
+	Subscriptions(ctx context.Context) ([]WebhookSubscription, error)
+	LogDelivery(ctx context.Context, delivery WebhookDelivery) error
+	DeadLetter(ctx context.Context, delivery WebhookDelivery) error
+	// Deliveries returns the latest deliveries, or dead letters, matching filter.
+	Deliveries(ctx context.Context, filter bson.M, dead bool, limit int64) ([]WebhookDelivery, error)
+}
+
+type collectionWebhookStore struct {
+	subscriptions *mongo.Collection
+	deliveries    *mongo.Collection
+	deadLetters   *mongo.Collection
+}
+
+func (s collectionWebhookStore) Subscriptions(ctx context.Context) ([]WebhookSubscription, error) {
+	cursor, err := s.subscriptions.Find(ctx, bson.M{})
+	if err != nil {
+		return nil, err
+	}
+	result := make([]WebhookSubscription, 0)
+	err = cursor.All(ctx, &result)
+	return result, err
+}
+
+func (s collectionWebhookStore) LogDelivery(ctx context.Context, delivery WebhookDelivery) error {
+	_, err := s.deliveries.InsertOne(ctx, delivery)
+	return err
+}
+
+func (s collectionWebhookStore) DeadLetter(ctx context.Context, delivery WebhookDelivery) error {
+	_, err := s.deadLetters.InsertOne(ctx, delivery)
+	return err
+}
+
+func (s collectionWebhookStore) Deliveries(ctx context.Context, filter bson.M, dead bool, limit int64) ([]WebhookDelivery, error) {
+	col := s.deliveries
+	if dead {
+		col = s.deadLetters
+	}
+	cursor, err := col.Find(ctx, filter, options.Find().SetSort(bson.M{"ts": -1}).SetLimit(limit))
+	if err != nil {
+		return nil, err
+	}
+	result := make([]WebhookDelivery, 0)
+	err = cursor.All(ctx, &result)
+	return result, err
+}
+
+type memoryWebhookStore struct {
+	mu            sync.Mutex
+	subscriptions []WebhookSubscription
+	deliveries    []WebhookDelivery
+	deadLetters   []WebhookDelivery
+}
+
+// NewMemoryWebhookStore keeps the subscriptions, deliveries and dead letters in memory, e.g.
+// for tests or a fixed set of subscriptions.
+func NewMemoryWebhookStore(subscriptions ...WebhookSubscription) WebhookStore {
+	return &memoryWebhookStore{subscriptions: subscriptions}
+}
+
+func (s *memoryWebhookStore) Subscriptions(ctx context.Context) ([]WebhookSubscription, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]WebhookSubscription{}, s.subscriptions...), nil
+}
+
+func (s *memoryWebhookStore) LogDelivery(ctx context.Context, delivery WebhookDelivery) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.deliveries = append(s.deliveries, delivery)
+	return nil
+}
+
+func (s *memoryWebhookStore) DeadLetter(ctx context.Context, delivery WebhookDelivery) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.deadLetters = append(s.deadLetters, delivery)
+	return nil
+}
+
+func (s *memoryWebhookStore) Deliveries(ctx context.Context, filter bson.M, dead bool, limit int64) ([]WebhookDelivery, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	records := s.deliveries
+	if dead {
+		records = s.deadLetters
+	}
+	result := make([]WebhookDelivery, 0)
+	for i := len(records) - 1; i >= 0 && (limit <= 0 || int64(len(result)) < limit); i-- {
+		data, err := bson.Marshal(records[i])
+		if err != nil {
+			return nil, err
+		}
+		var doc bson.M
+		if err := bson.Unmarshal(data, &doc); err != nil {
+			return nil, err
+		}
+		if matchFilter(doc, filter) {
+			result = append(result, records[i])
+		}
+	}
+	return result, nil
+}
+
+// SignWebhook returns the value of the X-Webhook-Signature header, the hex encoded
+// HMAC-SHA256 of the X-Webhook-Timestamp header, a dot and the body, prefixed with
+// "sha256=". Receivers recompute it to verify a request.
+func SignWebhook(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+type webhookJob struct {
+	subscription WebhookSubscription
+	event        WebhookEvent
+	body         []byte
+	attempt      int
+	backoff      time.Duration
+}
+
+// Webhooks delivers published events to the matching subscriptions. Deliveries are queued
+// and sent by a fixed number of workers, so publishing never blocks a write. Retries are
+// queued again once their backoff has passed, they do not hold a worker while waiting.
+type Webhooks struct {
+	config        WebhookConfig
+	store         WebhookStore
+	logger        Logger
+	mu            sync.RWMutex
+	subscriptions []WebhookSubscription
+	queue         chan webhookJob
+	dropped       int64
+	retryMu       sync.Mutex
+	retries       map[*time.Timer]webhookJob
+	stop          chan struct{}
+	once          sync.Once
+	wg            sync.WaitGroup
+	internal      []string
+}
+
+// NewWebhooks starts the delivery workers and the refresh of the subscriptions of the store
+// in config. Close stops them.
+func NewWebhooks(config WebhookConfig, logger Logger) (*Webhooks, error) {
+	if config.Store == nil {
+		return nil, errWebhookStoreMissing
+	}
+	if config.MaxAttempts <= 0 {
+		config.MaxAttempts = defaultWebhookMaxAttempts
+	}
+	if config.Backoff <= 0 {
+		config.Backoff = defaultWebhookBackoff
+	}
+	if config.MaxBackoff <= 0 {
+		config.MaxBackoff = defaultWebhookMaxBackoff
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = defaultWebhookTimeout
+	}
+	if config.Workers <= 0 {
+		config.Workers = defaultWebhookWorkers
+	}
+	if config.QueueSize <= 0 {
+		config.QueueSize = defaultWebhookQueueSize
+	}
+	if config.RefreshInterval <= 0 {
+		config.RefreshInterval = defaultWebhookRefreshInterval
+	}
+	if config.Client == nil {
+		config.Client = &http.Client{Timeout: config.Timeout}
+	}
+	if logger == nil {
+		logger = defaultLogger()
+	}
+	w := &Webhooks{
+		config:  config,
+		store:   config.Store,
+		logger:  logger,
+		queue:   make(chan webhookJob, config.QueueSize),
+		retries: map[*time.Timer]webhookJob{},
+		stop:    make(chan struct{}),
+	}
+	if err := w.Reload(Ctx()); err != nil {
+		logger.Error("loading webhook subscriptions failed", "error", err)
+	}
+	for i := 0; i < config.Workers; i++ {
+		w.wg.Add(1)
+		go w.work()
+	}
+	w.wg.Add(1)
+	go w.refresh()
+	return w, nil
+}
+
+func newMongoWebhooks(config WebhookConfig, client *mongo.Client, logger Logger) (*Webhooks, error) {
+	if config.Database == "" {
+		config.Database = defaultWebhookDatabase
+	}
+	if config.Collection == "" {
+		config.Collection = defaultWebhookCollection
+	}
+	if config.Store == nil {
+		db := client.Database(config.Database)
+		config.Store = collectionWebhookStore{
+			subscriptions: db.Collection(config.Collection),
+			deliveries:    db.Collection(config.Collection + webhookDeliveriesSuffix),
+			deadLetters:   db.Collection(config.Collection + webhookDeadLettersSuffix),
+		}
+	}
+	w, err := NewWebhooks(config, logger)
+	if err != nil {
+		return nil, err
+	}
+	// writes to the webhook collections publish no events, they would deliver themselves
+	for _, suffix := range []string{"", webhookDeliveriesSuffix, webhookDeadLettersSuffix} {
+		w.internal = append(w.internal, config.Database+"."+config.Collection+suffix)
+	}
+	if config.ChangeStreams {
+		w.wg.Add(1)
+		go w.watch(client)
+	}
+	return w, nil
+}
+
+// Reload reads the subscriptions from the store, they are also reloaded periodically and
+// after writes to the subscription collection.
+func (w *Webhooks) Reload(ctx context.Context) error {
+	subscriptions, err := w.store.Subscriptions(ctx)
+	if err != nil {
+		return err
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.subscriptions = subscriptions
+	return nil
+}
+
+// Close stops the workers. Deliveries waiting for a retry or in the queue are moved to the
+// dead letters.
+func (w *Webhooks) Close() {
+	w.once.Do(func() { close(w.stop) })
+	w.wg.Wait()
+	w.retryMu.Lock()
+	var waiting []webhookJob
+	for timer, job := range w.retries {
+		if timer.Stop() {
+			waiting = append(waiting, job)
+		}
+		delete(w.retries, timer)
+	}
+	w.retryMu.Unlock()
+	for _, job := range waiting {
+		w.deadLetter(job.delivery(errWebhooksClosed))
+	}
+	for {
+		select {
+		case job := <-w.queue:
+			w.deadLetter(job.delivery(errWebhooksClosed))
+		default:
+			return
+		}
+	}
+}
+
+// Deliveries returns the latest delivery attempts, or dead letters, matching filter.
+func (w *Webhooks) Deliveries(ctx context.Context, filter bson.M, dead bool, limit int64) ([]WebhookDelivery, error) {
+	return w.store.Deliveries(ctx, filter, dead, limit)
+}
+
+// Publish queues the event for all matching subscriptions.
+func (w *Webhooks) Publish(event WebhookEvent) {
+	namespace := event.Database + "." + event.Collection
+	for _, internal := range w.internal {
+		if namespace == internal {
+			if internal == w.internal[0] {
+				go w.reload()
+			}
+			return
+		}
+	}
+	if event.ID == "" {
+		event.ID = uuid.New().String()
+	}
+	if event.Timestamp.IsZero() {
+		event.Timestamp = time.Now()
+	}
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	for _, subscription := range w.subscriptions {
+		if !subscription.matches(event) {
+			continue
+		}
+		select {
+		case w.queue <- webhookJob{subscription: subscription, event: event, attempt: 1, backoff: w.config.Backoff}:
+		default:
+			// storing a dead letter would block the write, the event is only logged
+			w.logger.Warn("webhook queue is full, event dropped", "url", subscription.URL, "event", event.ID,
+				"dropped", atomic.AddInt64(&w.dropped, 1))
+		}
+	}
+}
+
+func (s WebhookSubscription) matches(event WebhookEvent) bool {
+	if s.Disabled || !matchNamespace([]string{s.Namespace}, event.Database, event.Collection) {
+		return false
+	}
+	if len(s.Operations) > 0 {
+		found := false
+		for _, op := range s.Operations {
+			found = found || op == event.Operation
+		}
+		if !found {
+			return false
+		}
+	}
+	if len(s.Filter) == 0 {
+		return true
+	}
+	return event.Document != nil && matchFilter(event.Document, s.Filter)
+}
+
+func (w *Webhooks) reload() {
+	if err := w.Reload(Ctx()); err != nil {
+		w.logger.Error("loading webhook subscriptions failed", "error", err)
+	}
+}
+
+func (w *Webhooks) refresh() {
+	defer w.wg.Done()
+	ticker := time.NewTicker(w.config.RefreshInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-w.stop:
+			return
+		case <-ticker.C:
+			w.reload()
+		}
+	}
+}
+
+func (w *Webhooks) work() {
+	defer w.wg.Done()
+	for {
+		select {
+		case <-w.stop:
+			return
+		case job := <-w.queue:
+			w.deliver(job)
+		}
+	}
+}
+
+// deliver makes one attempt to post the event. Failed attempts are retried after the backoff
+// until the attempts are exhausted or the receiver rejects the event with a client error
+// other than 408 and 429.
+func (w *Webhooks) deliver(job webhookJob) {
+	if job.body == nil {
+		body, err := bson.MarshalExtJSON(job.event, false, false)
+		if err != nil {
+			w.deadLetter(job.delivery(err))
+			return
+		}
+		job.body = body
+	}
+	status, err := w.post(job)
+	delivery := job.delivery(err)
+	delivery.Status = status
+	if logErr := w.store.LogDelivery(Ctx(), delivery); logErr != nil {
+		w.logger.Error("logging webhook delivery failed", "error", logErr, "url", job.subscription.URL)
+	}
+	if err == nil {
+		return
+	}
+	permanent := status >= http.StatusBadRequest && status < http.StatusInternalServerError &&
+		status != http.StatusRequestTimeout && status != http.StatusTooManyRequests
+	if permanent || job.attempt >= w.config.MaxAttempts {
+		w.deadLetter(delivery)
+		return
+	}
+	w.retry(job)
+}
+
+// retry queues the next attempt of job once its backoff has passed.
+func (w *Webhooks) retry(job webhookJob) {
+	w.retryMu.Lock()
+	if len(w.retries) >= w.config.QueueSize {
+		w.retryMu.Unlock()
+		w.deadLetter(job.delivery(errWebhookRetriesFull))
+		return
+	}
+	defer w.retryMu.Unlock()
+	wait := job.backoff
+	job.attempt++
+	job.backoff *= 2
+	if job.backoff > w.config.MaxBackoff {
+		job.backoff = w.config.MaxBackoff
+	}
+	var timer *time.Timer
+	timer = time.AfterFunc(wait, func() {
+		w.retryMu.Lock()
+		delete(w.retries, timer)
+		var err error
+		select {
+		case <-w.stop:
+			err = errWebhooksClosed
+		default:
+			select {
+			case w.queue <- job:
+			default:
+				err = errWebhookQueueFull
+			}
+		}
+		w.retryMu.Unlock()
+		if err != nil {
+			w.deadLetter(job.delivery(err))
+		}
+	})
+	w.retries[timer] = job
+}
+
+// delivery returns the log record of the current attempt of job, failed with err if not nil.
+func (job webhookJob) delivery(err error) WebhookDelivery {
+	delivery := WebhookDelivery{Subscription: job.subscription.ID, URL: job.subscription.URL, Event: job.event,
+		Attempt: job.attempt, Timestamp: time.Now()}
+	if err != nil {
+		delivery.Error = err.Error()
+	}
+	return delivery
+}
+
+func (w *Webhooks) post(job webhookJob) (int, error) {
+	ctx, cancel := context.WithTimeout(Ctx(), w.config.Timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, job.subscription.URL, bytes.NewReader(job.body))
+	if err != nil {
+		return 0, err
+	}
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", jsonContentType)
+	req.Header.Set(WebhookIDHeader, job.event.ID)
+	req.Header.Set(WebhookEventHeader, job.event.Operation)
+	req.Header.Set(WebhookTimestampHeader, timestamp)
+	if job.subscription.Secret != "" {
+		req.Header.Set(WebhookSignatureHeader, SignWebhook(job.subscription.Secret, timestamp, job.body))
+	}
+	resp, err := w.config.Client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= http.StatusMultipleChoices {
+		return resp.StatusCode, fmt.Errorf("webhook answered %s", resp.Status)
+	}
+	return resp.StatusCode, nil
+}
+
+func (w *Webhooks) deadLetter(delivery WebhookDelivery) {
+	w.logger.Warn("webhook delivery failed", "url", delivery.URL, "event", delivery.Event.ID, "attempts", delivery.Attempt, "error", delivery.Error)
+	if err := w.store.DeadLetter(Ctx(), delivery); err != nil {
+		w.logger.Error("storing webhook dead letter failed", "error", err, "url", delivery.URL)
+	}
+}
+
+// changeEvent is the part of a change stream event webhooks need.
+type changeEvent struct {
+	OperationType string `bson:"operationType"`
+	Namespace     struct {
+		Database   string `bson:"db"`
+		Collection string `bson:"coll"`
+	} `bson:"ns"`
+	DocumentKey  bson.M `bson:"documentKey"`
+	FullDocument bson.M `bson:"fullDocument"`
+}
+
+var changeOperations = map[string]string{
+	"insert":       AuditInsert,
+	"replace":      AuditReplace,
+	"update":       AuditUpdate,
+	"delete":       AuditDelete,
+	"drop":         AuditDropCollection,
+	"dropDatabase": AuditDropDatabase,
+}
+
+// watch publishes the events of a change stream of the deployment. The stream is resumed
+// after errors.
+func (w *Webhooks) watch(client *mongo.Client) {
+	defer w.wg.Done()
+	ctx, cancel := context.WithCancel(Ctx())
+	defer cancel()
+	go func() {
+		<-w.stop
+		cancel()
+	}()
+	var resumeToken bson.Raw
+	for {
+		opts := options.ChangeStream().SetFullDocument(options.UpdateLookup)
+		if resumeToken != nil {
+			opts.SetResumeAfter(resumeToken)
+		}
+		stream, err := client.Watch(ctx, mongo.Pipeline{}, opts)
+		if err == nil {
+			for stream.Next(ctx) {
+				resumeToken = stream.ResumeToken()
+				var change changeEvent
+				if err := stream.Decode(&change); err != nil {
+					w.logger.Error("decoding change event failed", "error", err)
+					continue
+				}
+				if op, ok := changeOperations[change.OperationType]; ok {
+					doc := change.FullDocument
+					if doc == nil {
+						// deleted documents are gone, the key is all filters can be matched against
+						doc = change.DocumentKey
+					}
+					w.Publish(WebhookEvent{
+						Operation:  op,
+						Database:   change.Namespace.Database,
+						Collection: change.Namespace.Collection,
+						DocumentID: change.DocumentKey[documentIDField],
+						Document:   doc,
+					})
+				}
+			}
+			err = stream.Err()
+			stream.Close(Ctx())
+		}
+		select {
+		case <-w.stop:
+			return
+		default:
+		}
+		if err != nil {
+			w.logger.Error("webhook change stream failed, resuming", "error", err)
+		}
+		select {
+		case <-w.stop:
+			return
+		case <-time.After(w.config.Backoff):
+		}
+	}
+}
+
+// publishChange publishes a mutation of the MongoClient unless events come from change
+// streams. The document is the changed one, or the deleted one for deletes.
+func (b MongoClient) publishChange(op, database, collection string, id interface{}, before, after bson.M) {
+	if !b.publishesChanges() {
+		return
+	}
+	doc := after
+	if doc == nil {
+		doc = before
+	}
+	ctx := b.Context()
+	b.webhooks.Publish(WebhookEvent{
+		Operation:  op,
+		Database:   database,
+		Collection: collection,
+		DocumentID: id,
+		Document:   doc,
+		Principal:  PrincipalFromContext(ctx),
+		RequestID:  RequestIDFromContext(ctx),
+	})
+}
+
+func (b MongoClient) publishesChanges() bool {
+	return b.webhooks != nil && !b.webhooks.config.ChangeStreams
+}
+
+// Webhooks returns the webhooks of the client, e.g. to publish custom events, or nil.
+func (b MongoClient) Webhooks() *Webhooks {
+	return b.webhooks
+}
+
+// GetWebhookDeliveries answers with the latest delivery attempts, or with dead=true the dead
+// letters, filtered by the subscription, op and documentId query parameters.
+func GetWebhookDeliveries(mongoClient *MongoClient) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		client := mongoClient.WithContext(r.Context())
+		if checkError(client.webhooksEnabled(), w, r) {
+			return
+		}
+		query := r.URL.Query()
+		limit := int64(defaultAuditLimit)
+		if v := query.Get(limitParam); v != "" {
+			parsed, err := strconv.ParseInt(v, 10, 64)
+			if checkError(err, w, r) {
+				return
+			}
+			limit = parsed
+		}
+		dead, _ := strconv.ParseBool(query.Get("dead"))
+		filter := bson.M{}
+		if v := query.Get("op"); v != "" {
+			filter["event.op"] = v
+		}
+		for param, field := range map[string]string{"subscription": "subscription", "documentId": "event.documentId"} {
+			if v := query.Get(param); v != "" {
+				idFilter, err := documentFilter(v)
+				if checkError(err, w, r) {
+					return
+				}
+				filter[field] = idFilter[documentIDField]
+			}
+		}
+		data, err := client.webhooks.Deliveries(client.Context(), filter, dead, limit)
+		if checkError(err, w, r) {
+			return
+		}
+		writeResponse(w, r, data)
+	}
+}
+
+func (b MongoClient) webhooksEnabled() error {
+	if b.webhooks == nil {
+		return errWebhooksDisabled
+	}
+	return nil
+}